@@ -2,21 +2,28 @@ package handler
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/afif-musyayyidin/hertz-boilerplate/api/service"
 	"github.com/afif-musyayyidin/hertz-boilerplate/domain/articles"
 	"github.com/afif-musyayyidin/hertz-boilerplate/domain/authors"
 	"github.com/afif-musyayyidin/hertz-boilerplate/domain/infra"
+	"github.com/afif-musyayyidin/hertz-boilerplate/middleware"
 	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/google/uuid"
 )
 
 type AppHandler struct {
-	svc *service.Service
+	svc  *service.Service
+	keys *middleware.KeySet
 }
 
-func NewAppHandler(svc *service.Service) *AppHandler {
-	return &AppHandler{svc: svc}
+func NewAppHandler(svc *service.Service, keys *middleware.KeySet) *AppHandler {
+	return &AppHandler{svc: svc, keys: keys}
 }
 
 // @Summary Create author
@@ -37,13 +44,96 @@ func (h *AppHandler) CreateAuthor(ctx context.Context, c *app.RequestContext) {
 
 	id, err := h.svc.CreateAuthor(ctx, author)
 	if err != nil {
-		infra.JSONError(c, 500, "Internal Server Error", err)
+		infra.JSONErrorFrom(c, err)
 		return
 	}
 
 	infra.JSONSuccess(c, id, "Author created successfully")
 }
 
+// @Summary Get author by ID or slug
+// @Tags Author
+// @Accept json
+// @Produce json
+// @Param id path string true "Author ID or slug"
+// @Success 200 {object} authors.AuthorPublic
+// @Failure 404 {object} infra.ErrorResponse
+// @Failure 500 {object} infra.ErrorResponse
+// @Router /author/{id} [get]
+func (h *AppHandler) GetAuthorByID(ctx context.Context, c *app.RequestContext) {
+	var (
+		author *authors.Author
+		err    error
+	)
+	if id, parseErr := uuid.Parse(c.Param("id")); parseErr == nil {
+		author, err = h.svc.GetAuthorByID(ctx, id)
+	} else {
+		author, err = h.svc.GetAuthorBySlug(ctx, c.Param("id"))
+	}
+	if err != nil {
+		infra.JSONErrorFrom(c, err)
+		return
+	}
+
+	infra.JSONSuccess(c, authors.NewAuthorPublic(author), "Author detail")
+}
+
+// @Summary List authors
+// @Tags Author
+// @Accept json
+// @Produce json
+// @Param sort query string false "name (default), -name, created_at or -created_at"
+// @Param limit query int false "Page size (default 10, max 100)"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Success 200 {object} authors.AuthorPageResponse
+// @Failure 400 {object} infra.ErrorResponse
+// @Failure 500 {object} infra.ErrorResponse
+// @Router /author [get]
+func (h *AppHandler) ListAuthors(ctx context.Context, c *app.RequestContext) {
+	page, err := parseAuthorPageRequest(c)
+	if err != nil {
+		infra.JSONError(c, 400, "Bad Request", err)
+		return
+	}
+
+	authorPage, err := h.svc.ListAuthors(ctx, page)
+	if err != nil {
+		infra.JSONErrorFrom(c, err)
+		return
+	}
+
+	infra.JSONSuccess(c, authors.NewAuthorPageResponse(authorPage), "Author list")
+}
+
+// @Summary Search authors by name
+// @Description Case-insensitive partial match on the author name.
+// @Tags Author
+// @Accept json
+// @Produce json
+// @Param q query string true "Part of the name"
+// @Param sort query string false "name (default), -name, created_at or -created_at"
+// @Param limit query int false "Page size (default 10, max 100)"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Success 200 {object} authors.AuthorPageResponse
+// @Failure 400 {object} infra.ErrorResponse
+// @Failure 500 {object} infra.ErrorResponse
+// @Router /author/search [get]
+func (h *AppHandler) SearchAuthors(ctx context.Context, c *app.RequestContext) {
+	page, err := parseAuthorPageRequest(c)
+	if err != nil {
+		infra.JSONError(c, 400, "Bad Request", err)
+		return
+	}
+
+	authorPage, err := h.svc.SearchAuthors(ctx, c.Query("q"), page)
+	if err != nil {
+		infra.JSONErrorFrom(c, err)
+		return
+	}
+
+	infra.JSONSuccess(c, authors.NewAuthorPageResponse(authorPage), "Author list")
+}
+
 // @Summary Create article
 // @Tags Article
 // @Accept json
@@ -68,7 +158,7 @@ func (h *AppHandler) CreateArticle(ctx context.Context, c *app.RequestContext) {
 
 	id, err := h.svc.CreateArticle(ctx, &article, uuid.MustParse(authorID))
 	if err != nil {
-		infra.JSONError(c, 500, "Internal Server Error", err)
+		infra.JSONErrorFrom(c, err)
 		return
 	}
 
@@ -99,7 +189,7 @@ func (h *AppHandler) CreateManyArticle(ctx context.Context, c *app.RequestContex
 
 	idList, err := h.svc.CreateManyArticle(ctx, articleList, uuid.MustParse(authorID))
 	if err != nil {
-		infra.JSONError(c, 500, "Internal Server Error", err)
+		infra.JSONErrorFrom(c, err)
 		return
 	}
 
@@ -111,10 +201,16 @@ func (h *AppHandler) CreateManyArticle(ctx context.Context, c *app.RequestContex
 // @Accept json
 // @Produce json
 // @Param id path string true "Article ID"
+// @Param If-Match header string true "ETag of the article version being edited"
 // @Security BearerAuth
 // @Param article body articles.ArticleInput true "Article input"
 // @Success 200 {object} string "UUID"
+// @Header 200 {string} ETag "Version of the updated article"
 // @Failure 400 {object} infra.ErrorResponse
+// @Failure 403 {object} infra.ErrorResponse
+// @Failure 404 {object} infra.ErrorResponse
+// @Failure 412 {object} infra.ErrorResponse
+// @Failure 428 {object} infra.ErrorResponse
 // @Failure 500 {object} infra.ErrorResponse
 // @Router /article/update/{id} [put]
 func (h *AppHandler) UpdateArticle(ctx context.Context, c *app.RequestContext) {
@@ -129,44 +225,181 @@ func (h *AppHandler) UpdateArticle(ctx context.Context, c *app.RequestContext) {
 		return
 	}
 
-	idArticle := c.Param("id")
-	if idArticle == "" {
-		infra.JSONError(c, 400, "Missing ID", nil)
+	idArticle, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		infra.JSONError(c, 400, "Bad Request", err)
+		return
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		infra.JSONErrorFrom(c, err)
 		return
 	}
 
-	id, err := h.svc.UpdateArticle(ctx, &article, uuid.MustParse(idArticle), uuid.MustParse(authorID))
+	updated, err := h.svc.UpdateArticle(ctx, &article, idArticle, uuid.MustParse(authorID), version)
 	if err != nil {
-		infra.JSONError(c, 500, "Internal Server Error", err)
+		infra.JSONErrorFrom(c, err)
 		return
 	}
 
-	infra.JSONSuccess(c, id, "Article updated successfully")
+	c.Header("ETag", articleETag(updated.Version))
+	infra.JSONSuccess(c, updated.ID, "Article updated successfully")
 }
 
-// @Summary Get article by key word
+// @Summary Search articles
+// @Description Relevance search with filters. Without a keyword every article passing the filters is returned, newest first. The response includes facet counts per author, month and status.
 // @Tags Article
 // @Accept json
 // @Produce json
-// @Param keyword query string true "Keyword"
-// @Success 200 {array} articles.Article
+// @Param keyword query string false "Keyword"
+// @Param author_ids query string false "Comma separated author IDs"
+// @Param status query string false "Comma separated statuses"
+// @Param date_field query string false "Date field filtered by from/to and bucketed by month: created_at (default) or updated_at"
+// @Param from query string false "Start date, RFC3339 or YYYY-MM-DD (inclusive)"
+// @Param to query string false "End date, RFC3339 (exclusive) or YYYY-MM-DD (inclusive)"
+// @Param limit query int false "Page size (default 10, max 100)"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param fields query string false "Comma separated article fields to return, e.g. title,created_at (default all)"
+// @Param fragment_size query int false "Highlight fragment size in characters (default 150)"
+// @Param pre_tag query string false "Tag opening a highlighted term: <em> (default), <strong>, <mark>, <b> or <i>"
+// @Param post_tag query string false "Tag closing a highlighted term: </em> (default), </strong>, </mark>, </b> or </i>"
+// @Success 200 {object} articles.ArticlePageResponse
 // @Failure 400 {object} infra.ErrorResponse
 // @Failure 500 {object} infra.ErrorResponse
 // @Router /article/search [get]
 func (h *AppHandler) GetArticleByKeyWord(ctx context.Context, c *app.RequestContext) {
-	keyword := c.Query("keyword")
-	if keyword == "" {
-		infra.JSONError(c, 400, "Bad Request", nil)
+	page, err := parsePageRequest(c)
+	if err != nil {
+		infra.JSONError(c, 400, "Bad Request", err)
+		return
+	}
+
+	req, err := parseSearchRequest(c)
+	if err != nil {
+		infra.JSONError(c, 400, "Bad Request", err)
+		return
+	}
+
+	articlePage, err := h.svc.GetArticleByKeyWord(ctx, req, viewerID(c), page)
+	if err != nil {
+		infra.JSONErrorFrom(c, err)
+		return
+	}
+
+	infra.JSONSuccess(c, articles.NewArticlePageResponse(articlePage), "Article list")
+}
+
+// @Summary Suggest article titles and author names
+// @Description Suggestions for a search box, from 2 typed characters on. Article titles and author names are ranked together by score.
+// @Tags Article
+// @Accept json
+// @Produce json
+// @Param q query string true "Typed text"
+// @Param size query int false "Number of suggestions (default 8, max 20)"
+// @Success 200 {array} articles.Suggestion
+// @Failure 400 {object} infra.ErrorResponse
+// @Failure 500 {object} infra.ErrorResponse
+// @Router /article/suggest [get]
+func (h *AppHandler) SuggestArticle(ctx context.Context, c *app.RequestContext) {
+	size := 0
+	if raw := c.Query("size"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			infra.JSONError(c, 400, "Bad Request", err)
+			return
+		}
+		size = n
+	}
+
+	suggestions, err := h.svc.SuggestArticle(ctx, c.Query("q"), viewerID(c), size)
+	if err != nil {
+		infra.JSONErrorFrom(c, err)
+		return
+	}
+
+	infra.JSONSuccess(c, suggestions, "Suggestion list")
+}
+
+// @Summary Get article by ID
+// @Description Returns the article with its author. Unpublished articles are only visible to their author, editors and admins. Send the ETag back in If-None-Match to get a 304 when the article and its author are unchanged, or in If-Match to update the article.
+// @Tags Article
+// @Accept json
+// @Produce json
+// @Param id path string true "Article ID"
+// @Param If-None-Match header string false "ETag of the cached article"
+// @Success 200 {object} articles.ArticleResponse
+// @Success 304 "Not Modified"
+// @Failure 400 {object} infra.ErrorResponse
+// @Failure 404 {object} infra.ErrorResponse
+// @Failure 500 {object} infra.ErrorResponse
+// @Router /article/{id} [get]
+func (h *AppHandler) GetArticleByID(ctx context.Context, c *app.RequestContext) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		infra.JSONError(c, 400, "Bad Request", err)
+		return
+	}
+
+	article, err := h.svc.GetArticleByID(ctx, id, viewerID(c))
+	if err != nil {
+		infra.JSONErrorFrom(c, err)
+		return
+	}
+
+	etag := articleDetailETag(article)
+	c.Header("ETag", etag)
+	c.Header("Vary", "Authorization")
+	// only published articles may be stored by shared caches
+	if article.Status == articles.StatusPublished {
+		c.Header("Cache-Control", "public, max-age="+strconv.Itoa(articleMaxAge))
+	} else {
+		c.Header("Cache-Control", "private, no-cache")
+	}
+	if ifNoneMatch(c, etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	infra.JSONSuccess(c, articles.NewArticleResponse(article), "Article detail")
+}
+
+// @Summary Get related articles
+// @Description Published articles similar to the given one, for a "read next" module.
+// @Tags Article
+// @Accept json
+// @Produce json
+// @Param id path string true "Article ID"
+// @Param size query int false "Number of articles (default 5, max 20)"
+// @Success 200 {array} articles.ArticleResponse
+// @Failure 400 {object} infra.ErrorResponse
+// @Failure 404 {object} infra.ErrorResponse
+// @Failure 500 {object} infra.ErrorResponse
+// @Router /article/{id}/related [get]
+func (h *AppHandler) GetRelatedArticles(ctx context.Context, c *app.RequestContext) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		infra.JSONError(c, 400, "Bad Request", err)
 		return
 	}
 
-	articleList, err := h.svc.GetArticleByKeyWord(ctx, keyword)
+	size := 0
+	if raw := c.Query("size"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			infra.JSONError(c, 400, "Bad Request", err)
+			return
+		}
+		size = n
+	}
+
+	related, err := h.svc.GetRelatedArticles(ctx, id, viewerID(c), size)
 	if err != nil {
-		infra.JSONError(c, 500, "Internal Server Error", err)
+		infra.JSONErrorFrom(c, err)
 		return
 	}
 
-	infra.JSONSuccess(c, articleList, "Article list")
+	infra.JSONSuccess(c, articles.NewArticleListResponse(related), "Related article list")
 }
 
 // @Summary Get article with author by ID
@@ -174,64 +407,122 @@ func (h *AppHandler) GetArticleByKeyWord(ctx context.Context, c *app.RequestCont
 // @Accept json
 // @Produce json
 // @Param id path string true "Author ID"
-// @Success 200 {object} articles.ArticleWithAuthor
+// @Param limit query int false "Page size (default 10, max 100)"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Success 200 {object} articles.ArticleWithAuthorResponse
 // @Failure 400 {object} infra.ErrorResponse
 // @Failure 500 {object} infra.ErrorResponse
 // @Router /article/author/{id} [get]
 func (h *AppHandler) GetArticleWithAuthorByID(ctx context.Context, c *app.RequestContext) {
-	idAuthor := c.Param("id")
-	if idAuthor == "" {
-		infra.JSONError(c, 400, "Missing Author ID", nil)
+	idAuthor, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		infra.JSONError(c, 400, "Bad Request", err)
 		return
 	}
 
-	articleList, err := h.svc.GetArticleWithAuthorByID(ctx, uuid.MustParse(idAuthor))
+	page, err := parsePageRequest(c)
 	if err != nil {
-		infra.JSONError(c, 500, "Internal Server Error", err)
+		infra.JSONError(c, 400, "Bad Request", err)
+		return
+	}
+
+	articleWithAuthor, err := h.svc.GetArticleWithAuthorByID(ctx, idAuthor, viewerID(c), page)
+	if err != nil {
+		infra.JSONErrorFrom(c, err)
 		return
 	}
 
-	infra.JSONSuccess(c, articleList, "Article list")
+	infra.JSONSuccess(c, articles.NewArticleWithAuthorResponse(articleWithAuthor), "Article list")
 }
 
 // @Summary Update author
 // @Tags Author
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param id path string true "Author ID"
 // @Param author body authors.AuthorInput true "Author input"
 // @Success 200 {object} string "UUID"
 // @Failure 400 {object} infra.ErrorResponse
+// @Failure 403 {object} infra.ErrorResponse
 // @Failure 500 {object} infra.ErrorResponse
 // @Router /author/update/{id} [put]
 func (h *AppHandler) UpdateAuthor(ctx context.Context, c *app.RequestContext) {
+	authorID := c.GetString("author_id")
+	if authorID == "" {
+		infra.JSONError(c, 400, "Missing Author ID", nil)
+		return
+	}
+
 	var author authors.AuthorInput
 	if err := c.Bind(&author); err != nil {
 		infra.JSONError(c, 400, "Bad Request", err)
 		return
 	}
 
-	idAuthor := c.Param("id")
-	if idAuthor == "" {
-		infra.JSONError(c, 400, "missing author ID", nil)
+	idAuthor, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		infra.JSONError(c, 400, "Bad Request", err)
 		return
 	}
 
-	id, err := h.svc.UpdateAuthor(ctx, author, uuid.MustParse(idAuthor))
+	id, err := h.svc.UpdateAuthor(ctx, author, idAuthor, uuid.MustParse(authorID))
 	if err != nil {
-		infra.JSONError(c, 500, "Internal Server Error", err)
+		infra.JSONErrorFrom(c, err)
 		return
 	}
 
 	infra.JSONSuccess(c, id, "Author updated successfully")
 }
 
+// @Summary Change the role of an author (admin only)
+// @Tags Author
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Author ID"
+// @Param role body authors.AuthorRoleInput true "New role: author, editor or admin"
+// @Success 200 {object} string "UUID"
+// @Failure 400 {object} infra.ErrorResponse
+// @Failure 403 {object} infra.ErrorResponse
+// @Failure 404 {object} infra.ErrorResponse
+// @Failure 500 {object} infra.ErrorResponse
+// @Router /author/{id}/role [put]
+func (h *AppHandler) SetAuthorRole(ctx context.Context, c *app.RequestContext) {
+	authorID := c.GetString("author_id")
+	if authorID == "" {
+		infra.JSONError(c, 400, "Missing Author ID", nil)
+		return
+	}
+
+	var input authors.AuthorRoleInput
+	if err := c.Bind(&input); err != nil {
+		infra.JSONError(c, 400, "Bad Request", err)
+		return
+	}
+
+	idAuthor, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		infra.JSONError(c, 400, "Bad Request", err)
+		return
+	}
+
+	if err := h.svc.SetAuthorRole(ctx, idAuthor, input.Role, uuid.MustParse(authorID)); err != nil {
+		infra.JSONErrorFrom(c, err)
+		return
+	}
+
+	infra.JSONSuccess(c, idAuthor, "Author role updated successfully")
+}
+
 // @Summary Get article by author name
 // @Tags Article
 // @Accept json
 // @Produce json
 // @Param name query string true "Author name"
-// @Success 200 {array} articles.ArticleWithAuthor
+// @Param limit query int false "Page size (default 10, max 100)"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Success 200 {object} articles.ArticleWithAuthorPageResponse
 // @Failure 400 {object} infra.ErrorResponse
 // @Failure 500 {object} infra.ErrorResponse
 // @Router /article/author-name [get]
@@ -242,13 +533,19 @@ func (h *AppHandler) GetArticleByAuthorName(ctx context.Context, c *app.RequestC
 		return
 	}
 
-	articleList, err := h.svc.GetArticleByAuthorName(ctx, name)
+	page, err := parsePageRequest(c)
+	if err != nil {
+		infra.JSONError(c, 400, "Bad Request", err)
+		return
+	}
+
+	articleWithAuthorPage, err := h.svc.GetArticleByAuthorName(ctx, name, viewerID(c), page)
 	if err != nil {
-		infra.JSONError(c, 500, "Internal Server Error", err)
+		infra.JSONErrorFrom(c, err)
 		return
 	}
 
-	infra.JSONSuccess(c, articleList, "Article list")
+	infra.JSONSuccess(c, articles.NewArticleWithAuthorPageResponse(articleWithAuthorPage), "Article list")
 }
 
 // @Summary Login author
@@ -256,8 +553,10 @@ func (h *AppHandler) GetArticleByAuthorName(ctx context.Context, c *app.RequestC
 // @Accept json
 // @Produce json
 // @Param loginRequest body authors.LoginAuthorRequest true "Login request"
-// @Success 200 {object} string "Token"
+// @Success 200 {object} authors.TokenPair
 // @Failure 400 {object} infra.ErrorResponse
+// @Failure 401 {object} infra.ErrorResponse "Invalid email or password"
+// @Failure 429 {object} infra.ErrorResponse "Too many failed attempts; see Retry-After"
 // @Failure 500 {object} infra.ErrorResponse
 // @Router /author/login [post]
 func (h *AppHandler) LoginAuthor(ctx context.Context, c *app.RequestContext) {
@@ -266,34 +565,777 @@ func (h *AppHandler) LoginAuthor(ctx context.Context, c *app.RequestContext) {
 		infra.JSONError(c, 400, "Bad Request", err)
 		return
 	}
-	
+
 	if loginRequest.Email == "" || loginRequest.Password == "" {
 		infra.JSONError(c, 400, "missing email or password", nil)
 		return
 	}
 
-	token, err := h.svc.LoginAuthor(ctx, loginRequest.Email, loginRequest.Password)
+	token, err := h.svc.LoginAuthor(ctx, loginRequest.Email, loginRequest.Password, c.ClientIP())
 	if err != nil {
-		infra.JSONError(c, 500, "Internal Server Error", err)
-		return	
+		setRetryAfter(c, err)
+		infra.JSONErrorFrom(c, err)
+		return
 	}
 	infra.JSONSuccess(c, token, "Login successful")
 }
 
-// @Summary Get all article
-// @Tags Article
+// @Summary Exchange a refresh token for a new token pair
+// @Description Every refresh token works once. Presenting a used refresh token again revokes all tokens of that login.
+// @Tags Author
 // @Accept json
 // @Produce json
+// @Param refreshRequest body authors.RefreshTokenRequest true "Refresh token"
+// @Success 200 {object} authors.TokenPair
+// @Failure 400 {object} infra.ErrorResponse
+// @Failure 401 {object} infra.ErrorResponse
+// @Failure 500 {object} infra.ErrorResponse
+// @Router /author/token/refresh [post]
+func (h *AppHandler) RefreshToken(ctx context.Context, c *app.RequestContext) {
+	var refreshRequest authors.RefreshTokenRequest
+	if err := c.Bind(&refreshRequest); err != nil {
+		infra.JSONError(c, 400, "Bad Request", err)
+		return
+	}
+
+	if refreshRequest.RefreshToken == "" {
+		infra.JSONError(c, 400, "missing refresh token", nil)
+		return
+	}
+
+	token, err := h.svc.RefreshToken(ctx, refreshRequest.RefreshToken)
+	if err != nil {
+		infra.JSONErrorFrom(c, err)
+		return
+	}
+	infra.JSONSuccess(c, token, "Token refreshed")
+}
+
+// @Summary Logout author
+// @Description Revokes the access token and the refresh tokens of the same login.
+// @Tags Author
+// @Produce json
 // @Security BearerAuth
-// @Success 200 {array} articles.Article
+// @Success 200 {object} string "UUID"
 // @Failure 400 {object} infra.ErrorResponse
+// @Failure 401 {object} infra.ErrorResponse
 // @Failure 500 {object} infra.ErrorResponse
-// @Router /article/all [get]
-func (h *AppHandler) GetAllArticle(ctx context.Context, c *app.RequestContext) {
-	articleList, err := h.svc.GetAllArticle(ctx)
+// @Router /author/logout [post]
+func (h *AppHandler) Logout(ctx context.Context, c *app.RequestContext) {
+	authorID := c.GetString("author_id")
+	if authorID == "" {
+		infra.JSONError(c, 400, "Missing Author ID", nil)
+		return
+	}
+
+	tokenID, err := uuid.Parse(c.GetString("token_id"))
+	if err != nil {
+		infra.JSONError(c, 400, "Bad Request", err)
+		return
+	}
+
+	if err := h.svc.Logout(ctx, uuid.MustParse(authorID), tokenID, c.GetTime("token_expires_at")); err != nil {
+		infra.JSONErrorFrom(c, err)
+		return
+	}
+	infra.JSONSuccess(c, authorID, "Logout successful")
+}
+
+// @Summary Change password
+// @Description Needs the current password. All sessions of the author are revoked and a new token pair is returned.
+// @Tags Author
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param changePasswordRequest body authors.ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} authors.TokenPair
+// @Failure 400 {object} infra.ErrorResponse
+// @Failure 401 {object} infra.ErrorResponse
+// @Failure 403 {object} infra.ErrorResponse "Current password is incorrect"
+// @Failure 429 {object} infra.ErrorResponse "Too many failed attempts; see Retry-After"
+// @Failure 500 {object} infra.ErrorResponse
+// @Router /author/password [post]
+func (h *AppHandler) ChangePassword(ctx context.Context, c *app.RequestContext) {
+	authorID := c.GetString("author_id")
+	if authorID == "" {
+		infra.JSONError(c, 400, "Missing Author ID", nil)
+		return
+	}
+
+	var changeRequest authors.ChangePasswordRequest
+	if err := c.Bind(&changeRequest); err != nil {
+		infra.JSONError(c, 400, "Bad Request", err)
+		return
+	}
+
+	if changeRequest.CurrentPassword == "" || changeRequest.NewPassword == "" {
+		infra.JSONError(c, 400, "missing current or new password", nil)
+		return
+	}
+
+	token, err := h.svc.ChangePassword(ctx, uuid.MustParse(authorID), changeRequest.CurrentPassword, changeRequest.NewPassword, c.ClientIP())
 	if err != nil {
-		infra.JSONError(c, 500, "Internal Server Error", err)
+		setRetryAfter(c, err)
+		infra.JSONErrorFrom(c, err)
+		return
+	}
+	infra.JSONSuccess(c, token, "Password changed")
+}
+
+// @Summary Request a password reset link
+// @Description Mails a single-use reset link when the email is registered. The answer is the same either way.
+// @Tags Author
+// @Accept json
+// @Produce json
+// @Param forgotPasswordRequest body authors.ForgotPasswordRequest true "Email of the account"
+// @Success 200 {object} string
+// @Failure 400 {object} infra.ErrorResponse
+// @Failure 500 {object} infra.ErrorResponse
+// @Router /author/password/forgot [post]
+func (h *AppHandler) ForgotPassword(ctx context.Context, c *app.RequestContext) {
+	var forgotRequest authors.ForgotPasswordRequest
+	if err := c.Bind(&forgotRequest); err != nil {
+		infra.JSONError(c, 400, "Bad Request", err)
+		return
+	}
+
+	if forgotRequest.Email == "" {
+		infra.JSONError(c, 400, "missing email", nil)
+		return
+	}
+
+	if err := h.svc.ForgotPassword(ctx, forgotRequest.Email); err != nil {
+		infra.JSONErrorFrom(c, err)
+		return
+	}
+	infra.JSONSuccess(c, nil, "If the email is registered, a reset link has been sent")
+}
+
+// @Summary Reset password
+// @Description Sets a new password with the token from the reset link. All sessions of the author are revoked.
+// @Tags Author
+// @Accept json
+// @Produce json
+// @Param resetPasswordRequest body authors.ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} string
+// @Failure 400 {object} infra.ErrorResponse
+// @Failure 500 {object} infra.ErrorResponse
+// @Router /author/password/reset [post]
+func (h *AppHandler) ResetPassword(ctx context.Context, c *app.RequestContext) {
+	var resetRequest authors.ResetPasswordRequest
+	if err := c.Bind(&resetRequest); err != nil {
+		infra.JSONError(c, 400, "Bad Request", err)
+		return
+	}
+
+	if resetRequest.Token == "" || resetRequest.NewPassword == "" {
+		infra.JSONError(c, 400, "missing token or new password", nil)
+		return
+	}
+
+	if err := h.svc.ResetPassword(ctx, resetRequest.Token, resetRequest.NewPassword); err != nil {
+		infra.JSONErrorFrom(c, err)
 		return
 	}
-	infra.JSONSuccess(c, articleList, "Article list")
-}
\ No newline at end of file
+	infra.JSONSuccess(c, nil, "Password reset")
+}
+
+// @Summary Verify email
+// @Description Opened from the verification link. Verifies the email of a new author, or completes a change of email.
+// @Tags Author
+// @Produce json
+// @Param token query string true "Token from the verification link"
+// @Success 200 {object} string
+// @Failure 400 {object} infra.ErrorResponse
+// @Failure 409 {object} infra.ErrorResponse "Email is already in use"
+// @Failure 500 {object} infra.ErrorResponse
+// @Router /author/verify [get]
+func (h *AppHandler) VerifyEmail(ctx context.Context, c *app.RequestContext) {
+	token := c.Query("token")
+	if token == "" {
+		infra.JSONError(c, 400, "missing token", nil)
+		return
+	}
+
+	if err := h.svc.VerifyEmail(ctx, token); err != nil {
+		infra.JSONErrorFrom(c, err)
+		return
+	}
+	infra.JSONSuccess(c, nil, "Email verified")
+}
+
+// @Summary Resend the verification email
+// @Description Sends a new link for the pending email, or for the current one while it is unverified.
+// @Tags Author
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} string
+// @Failure 400 {object} infra.ErrorResponse
+// @Failure 401 {object} infra.ErrorResponse
+// @Failure 409 {object} infra.ErrorResponse "Email is already verified"
+// @Failure 429 {object} infra.ErrorResponse "Sent recently; see Retry-After"
+// @Failure 500 {object} infra.ErrorResponse
+// @Router /author/verify/resend [post]
+func (h *AppHandler) ResendVerification(ctx context.Context, c *app.RequestContext) {
+	authorID := c.GetString("author_id")
+	if authorID == "" {
+		infra.JSONError(c, 400, "Missing Author ID", nil)
+		return
+	}
+
+	if err := h.svc.ResendVerification(ctx, uuid.MustParse(authorID)); err != nil {
+		setRetryAfter(c, err)
+		infra.JSONErrorFrom(c, err)
+		return
+	}
+	infra.JSONSuccess(c, nil, "Verification email sent")
+}
+
+// @Summary Get all article
+// @Tags Article
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param limit query int false "Page size (default 10, max 100)"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Success 200 {object} articles.ArticlePageResponse
+// @Failure 400 {object} infra.ErrorResponse
+// @Failure 500 {object} infra.ErrorResponse
+// @Router /article/all [get]
+func (h *AppHandler) GetAllArticle(ctx context.Context, c *app.RequestContext) {
+	page, err := parsePageRequest(c)
+	if err != nil {
+		infra.JSONError(c, 400, "Bad Request", err)
+		return
+	}
+
+	articlePage, err := h.svc.GetAllArticle(ctx, viewerID(c), page)
+	if err != nil {
+		infra.JSONErrorFrom(c, err)
+		return
+	}
+	infra.JSONSuccess(c, articles.NewArticlePageResponse(articlePage), "Article list")
+}
+
+// @Summary Delete article
+// @Tags Article
+// @Accept json
+// @Produce json
+// @Param id path string true "Article ID"
+// @Security BearerAuth
+// @Success 200 {object} string "UUID"
+// @Failure 400 {object} infra.ErrorResponse
+// @Failure 403 {object} infra.ErrorResponse
+// @Failure 404 {object} infra.ErrorResponse
+// @Failure 500 {object} infra.ErrorResponse
+// @Router /article/{id} [delete]
+func (h *AppHandler) DeleteArticle(ctx context.Context, c *app.RequestContext) {
+	authorID := c.GetString("author_id")
+	if authorID == "" {
+		infra.JSONError(c, 400, "Missing Author ID", nil)
+		return
+	}
+
+	idArticle, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		infra.JSONError(c, 400, "Bad Request", err)
+		return
+	}
+
+	if err := h.svc.DeleteArticle(ctx, idArticle, uuid.MustParse(authorID)); err != nil {
+		infra.JSONErrorFrom(c, err)
+		return
+	}
+
+	infra.JSONSuccess(c, idArticle, "Article deleted successfully")
+}
+
+// @Summary Restore deleted article
+// @Tags Article
+// @Accept json
+// @Produce json
+// @Param id path string true "Article ID"
+// @Security BearerAuth
+// @Success 200 {object} string "UUID"
+// @Failure 400 {object} infra.ErrorResponse
+// @Failure 403 {object} infra.ErrorResponse
+// @Failure 404 {object} infra.ErrorResponse
+// @Failure 500 {object} infra.ErrorResponse
+// @Router /article/{id}/restore [post]
+func (h *AppHandler) RestoreArticle(ctx context.Context, c *app.RequestContext) {
+	authorID := c.GetString("author_id")
+	if authorID == "" {
+		infra.JSONError(c, 400, "Missing Author ID", nil)
+		return
+	}
+
+	idArticle, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		infra.JSONError(c, 400, "Bad Request", err)
+		return
+	}
+
+	id, err := h.svc.RestoreArticle(ctx, idArticle, uuid.MustParse(authorID))
+	if err != nil {
+		infra.JSONErrorFrom(c, err)
+		return
+	}
+
+	infra.JSONSuccess(c, id, "Article restored successfully")
+}
+
+// @Summary Publish article
+// @Description The acting author needs a verified email.
+// @Tags Article
+// @Accept json
+// @Produce json
+// @Param id path string true "Article ID"
+// @Security BearerAuth
+// @Success 200 {object} string "UUID"
+// @Failure 400 {object} infra.ErrorResponse
+// @Failure 403 {object} infra.ErrorResponse
+// @Failure 404 {object} infra.ErrorResponse
+// @Failure 409 {object} infra.ErrorResponse
+// @Failure 500 {object} infra.ErrorResponse
+// @Router /article/{id}/publish [post]
+func (h *AppHandler) PublishArticle(ctx context.Context, c *app.RequestContext) {
+	authorID := c.GetString("author_id")
+	if authorID == "" {
+		infra.JSONError(c, 400, "Missing Author ID", nil)
+		return
+	}
+
+	idArticle, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		infra.JSONError(c, 400, "Bad Request", err)
+		return
+	}
+
+	id, err := h.svc.PublishArticle(ctx, idArticle, uuid.MustParse(authorID))
+	if err != nil {
+		infra.JSONErrorFrom(c, err)
+		return
+	}
+
+	infra.JSONSuccess(c, id, "Article published successfully")
+}
+
+// @Summary Unpublish article
+// @Tags Article
+// @Accept json
+// @Produce json
+// @Param id path string true "Article ID"
+// @Security BearerAuth
+// @Success 200 {object} string "UUID"
+// @Failure 400 {object} infra.ErrorResponse
+// @Failure 403 {object} infra.ErrorResponse
+// @Failure 404 {object} infra.ErrorResponse
+// @Failure 409 {object} infra.ErrorResponse
+// @Failure 500 {object} infra.ErrorResponse
+// @Router /article/{id}/unpublish [post]
+func (h *AppHandler) UnpublishArticle(ctx context.Context, c *app.RequestContext) {
+	authorID := c.GetString("author_id")
+	if authorID == "" {
+		infra.JSONError(c, 400, "Missing Author ID", nil)
+		return
+	}
+
+	idArticle, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		infra.JSONError(c, 400, "Bad Request", err)
+		return
+	}
+
+	id, err := h.svc.UnpublishArticle(ctx, idArticle, uuid.MustParse(authorID))
+	if err != nil {
+		infra.JSONErrorFrom(c, err)
+		return
+	}
+
+	infra.JSONSuccess(c, id, "Article unpublished successfully")
+}
+
+// @Summary Archive article
+// @Tags Article
+// @Accept json
+// @Produce json
+// @Param id path string true "Article ID"
+// @Security BearerAuth
+// @Success 200 {object} string "UUID"
+// @Failure 400 {object} infra.ErrorResponse
+// @Failure 403 {object} infra.ErrorResponse
+// @Failure 404 {object} infra.ErrorResponse
+// @Failure 409 {object} infra.ErrorResponse
+// @Failure 500 {object} infra.ErrorResponse
+// @Router /article/{id}/archive [post]
+func (h *AppHandler) ArchiveArticle(ctx context.Context, c *app.RequestContext) {
+	authorID := c.GetString("author_id")
+	if authorID == "" {
+		infra.JSONError(c, 400, "Missing Author ID", nil)
+		return
+	}
+
+	idArticle, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		infra.JSONError(c, 400, "Bad Request", err)
+		return
+	}
+
+	id, err := h.svc.ArchiveArticle(ctx, idArticle, uuid.MustParse(authorID))
+	if err != nil {
+		infra.JSONErrorFrom(c, err)
+		return
+	}
+
+	infra.JSONSuccess(c, id, "Article archived successfully")
+}
+
+// @Summary Schedule article publication
+// @Description The acting author needs a verified email.
+// @Tags Article
+// @Accept json
+// @Produce json
+// @Param id path string true "Article ID"
+// @Param schedule body articles.ScheduleArticleInput true "Publication time"
+// @Security BearerAuth
+// @Success 200 {object} string "UUID"
+// @Failure 400 {object} infra.ErrorResponse
+// @Failure 403 {object} infra.ErrorResponse
+// @Failure 404 {object} infra.ErrorResponse
+// @Failure 409 {object} infra.ErrorResponse
+// @Failure 500 {object} infra.ErrorResponse
+// @Router /article/{id}/schedule [post]
+func (h *AppHandler) ScheduleArticle(ctx context.Context, c *app.RequestContext) {
+	var schedule articles.ScheduleArticleInput
+	if err := c.Bind(&schedule); err != nil {
+		infra.JSONError(c, 400, "Bad Request", err)
+		return
+	}
+	authorID := c.GetString("author_id")
+	if authorID == "" {
+		infra.JSONError(c, 400, "Missing Author ID", nil)
+		return
+	}
+
+	idArticle, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		infra.JSONError(c, 400, "Bad Request", err)
+		return
+	}
+
+	id, err := h.svc.ScheduleArticle(ctx, idArticle, uuid.MustParse(authorID), schedule.PublishAt)
+	if err != nil {
+		infra.JSONErrorFrom(c, err)
+		return
+	}
+
+	infra.JSONSuccess(c, id, "Article scheduled successfully")
+}
+
+// @Summary List article revisions
+// @Tags Article
+// @Accept json
+// @Produce json
+// @Param id path string true "Article ID"
+// @Security BearerAuth
+// @Success 200 {array} articles.Revision
+// @Failure 400 {object} infra.ErrorResponse
+// @Failure 403 {object} infra.ErrorResponse
+// @Failure 404 {object} infra.ErrorResponse
+// @Failure 500 {object} infra.ErrorResponse
+// @Router /article/{id}/revisions [get]
+func (h *AppHandler) GetArticleRevisions(ctx context.Context, c *app.RequestContext) {
+	authorID := c.GetString("author_id")
+	if authorID == "" {
+		infra.JSONError(c, 400, "Missing Author ID", nil)
+		return
+	}
+
+	idArticle, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		infra.JSONError(c, 400, "Bad Request", err)
+		return
+	}
+
+	revisions, err := h.svc.GetArticleRevisions(ctx, idArticle, uuid.MustParse(authorID))
+	if err != nil {
+		infra.JSONErrorFrom(c, err)
+		return
+	}
+
+	infra.JSONSuccess(c, revisions, "Article revision list")
+}
+
+// @Summary Get article revision with diff against the current version
+// @Tags Article
+// @Accept json
+// @Produce json
+// @Param id path string true "Article ID"
+// @Param rev path int true "Revision number"
+// @Security BearerAuth
+// @Success 200 {object} articles.RevisionDiff
+// @Failure 400 {object} infra.ErrorResponse
+// @Failure 403 {object} infra.ErrorResponse
+// @Failure 404 {object} infra.ErrorResponse
+// @Failure 500 {object} infra.ErrorResponse
+// @Router /article/{id}/revisions/{rev} [get]
+func (h *AppHandler) GetArticleRevision(ctx context.Context, c *app.RequestContext) {
+	authorID := c.GetString("author_id")
+	if authorID == "" {
+		infra.JSONError(c, 400, "Missing Author ID", nil)
+		return
+	}
+
+	idArticle, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		infra.JSONError(c, 400, "Bad Request", err)
+		return
+	}
+
+	revision, err := strconv.Atoi(c.Param("rev"))
+	if err != nil {
+		infra.JSONError(c, 400, "Bad Request", err)
+		return
+	}
+
+	revisionDiff, err := h.svc.GetArticleRevision(ctx, idArticle, revision, uuid.MustParse(authorID))
+	if err != nil {
+		infra.JSONErrorFrom(c, err)
+		return
+	}
+
+	infra.JSONSuccess(c, revisionDiff, "Article revision")
+}
+
+// @Summary Restore article revision
+// @Tags Article
+// @Accept json
+// @Produce json
+// @Param id path string true "Article ID"
+// @Param rev path int true "Revision number"
+// @Security BearerAuth
+// @Success 200 {object} string "UUID"
+// @Failure 400 {object} infra.ErrorResponse
+// @Failure 403 {object} infra.ErrorResponse
+// @Failure 404 {object} infra.ErrorResponse
+// @Failure 500 {object} infra.ErrorResponse
+// @Router /article/{id}/revisions/{rev}/restore [post]
+func (h *AppHandler) RestoreArticleRevision(ctx context.Context, c *app.RequestContext) {
+	authorID := c.GetString("author_id")
+	if authorID == "" {
+		infra.JSONError(c, 400, "Missing Author ID", nil)
+		return
+	}
+
+	idArticle, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		infra.JSONError(c, 400, "Bad Request", err)
+		return
+	}
+
+	revision, err := strconv.Atoi(c.Param("rev"))
+	if err != nil {
+		infra.JSONError(c, 400, "Bad Request", err)
+		return
+	}
+
+	id, err := h.svc.RestoreArticleRevision(ctx, idArticle, revision, uuid.MustParse(authorID))
+	if err != nil {
+		infra.JSONErrorFrom(c, err)
+		return
+	}
+
+	infra.JSONSuccess(c, id, "Article revision restored successfully")
+}
+
+func parsePageRequest(c *app.RequestContext) (articles.PageRequest, error) {
+	page := articles.PageRequest{Cursor: c.Query("cursor")}
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return page, err
+		}
+		page.Limit = n
+	}
+	return page, nil
+}
+
+func parseAuthorPageRequest(c *app.RequestContext) (authors.PageRequest, error) {
+	page, err := parsePageRequest(c)
+	if err != nil {
+		return authors.PageRequest{}, err
+	}
+	return authors.PageRequest{
+		Sort:   authors.AuthorSort(c.Query("sort")),
+		Limit:  page.Limit,
+		Cursor: page.Cursor,
+	}, nil
+}
+
+func parseSearchRequest(c *app.RequestContext) (articles.SearchRequest, error) {
+	req := articles.SearchRequest{
+		Keyword:   c.Query("keyword"),
+		DateField: c.Query("date_field"),
+		Highlight: articles.HighlightOptions{
+			PreTag:  c.Query("pre_tag"),
+			PostTag: c.Query("post_tag"),
+		},
+	}
+	if authorIDs := c.Query("author_ids"); authorIDs != "" {
+		for _, raw := range strings.Split(authorIDs, ",") {
+			id, err := uuid.Parse(strings.TrimSpace(raw))
+			if err != nil {
+				return req, err
+			}
+			req.AuthorIDs = append(req.AuthorIDs, id)
+		}
+	}
+	if statuses := c.Query("status"); statuses != "" {
+		for _, status := range strings.Split(statuses, ",") {
+			req.Statuses = append(req.Statuses, articles.Status(strings.TrimSpace(status)))
+		}
+	}
+	if from := c.Query("from"); from != "" {
+		t, _, err := parseSearchDate(from)
+		if err != nil {
+			return req, err
+		}
+		req.From = &t
+	}
+	if to := c.Query("to"); to != "" {
+		t, dateOnly, err := parseSearchDate(to)
+		if err != nil {
+			return req, err
+		}
+		// a plain date includes the whole day
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		req.To = &t
+	}
+	if fields := c.Query("fields"); fields != "" {
+		req.Fields = strings.Split(fields, ",")
+	}
+	if fragmentSize := c.Query("fragment_size"); fragmentSize != "" {
+		n, err := strconv.Atoi(fragmentSize)
+		if err != nil {
+			return req, err
+		}
+		req.Highlight.FragmentSize = n
+	}
+	return req, nil
+}
+
+// parseSearchDate accepts an RFC3339 timestamp or a YYYY-MM-DD date, which is
+// read as midnight UTC.
+func parseSearchDate(value string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	return t, false, err
+}
+
+// articleMaxAge is how long, in seconds, caches may serve a published
+// article without revalidating it.
+const articleMaxAge = 60
+
+// jwksMaxAge is how long, in seconds, other services may cache the JWKS. A
+// new verification key has to be published at least this long before
+// tokens are signed with it.
+const jwksMaxAge = 300
+
+// articleETag is the strong ETag clients send back in If-Match.
+func articleETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// articleDetailETag is the ETag of an article served with its author. The
+// author's updated_at follows the version, so an edited profile is not
+// answered with 304; parseIfMatch only reads the version.
+func articleDetailETag(article *articles.Article) string {
+	if article.Author == nil {
+		return articleETag(article.Version)
+	}
+	return `"` + strconv.Itoa(article.Version) + "-" + strconv.FormatInt(article.Author.UpdatedAt.UnixMicro(), 36) + `"`
+}
+
+// ifNoneMatch reports whether the If-None-Match header matches etag. Weak
+// ETags match as well, as the header is compared weakly.
+func ifNoneMatch(c *app.RequestContext, etag string) bool {
+	for _, candidate := range strings.Split(string(c.GetHeader("If-None-Match")), ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// parseIfMatch reads the article version from the If-Match header, either
+// an articleETag or an articleDetailETag. A weak or malformed ETag can never
+// match an article version.
+func parseIfMatch(c *app.RequestContext) (int, error) {
+	ifMatch := string(c.GetHeader("If-Match"))
+	if ifMatch == "" {
+		return 0, articles.ErrVersionRequired
+	}
+	tag, _, _ := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(ifMatch, `"`), `"`), "-")
+	version, err := strconv.Atoi(tag)
+	if err != nil || version <= 0 {
+		return 0, articles.ErrVersionMismatch.WithDetails(map[string]interface{}{
+			"if_match": ifMatch,
+		})
+	}
+	return version, nil
+}
+
+// setRetryAfter copies the retry_after detail of a throttled request into
+// the Retry-After header.
+func setRetryAfter(c *app.RequestContext, err error) {
+	var apiErr *infra.APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode != infra.CodeTooManyRequests {
+		return
+	}
+	if seconds, ok := apiErr.Details["retry_after"].(int); ok {
+		c.Header("Retry-After", strconv.Itoa(seconds))
+	}
+}
+
+// viewerID returns the logged-in author, or uuid.Nil for anonymous callers.
+func viewerID(c *app.RequestContext) uuid.UUID {
+	id, err := uuid.Parse(c.GetString("author_id"))
+	if err != nil {
+		return uuid.Nil
+	}
+	return id
+}
+
+// @Summary Public keys that verify access tokens
+// @Description JSON Web Key Set (RFC 7517). Tokens name their key in the kid header.
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} middleware.JWKS
+// @Router /.well-known/jwks.json [get]
+func (h *AppHandler) JWKS(ctx context.Context, c *app.RequestContext) {
+	c.Header("Cache-Control", "public, max-age="+strconv.Itoa(jwksMaxAge))
+	c.JSON(http.StatusOK, h.keys.JWKS())
+}
+
+// @Summary Outbox relay counters and backlog (admin only)
+// @Tags Metrics
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} articles.OutboxStats
+// @Failure 401 {object} infra.ErrorResponse
+// @Failure 403 {object} infra.ErrorResponse
+// @Failure 500 {object} infra.ErrorResponse
+// @Router /metrics/outbox [get]
+func (h *AppHandler) OutboxStats(ctx context.Context, c *app.RequestContext) {
+	stats, err := h.svc.OutboxStats(ctx)
+	if err != nil {
+		infra.JSONErrorFrom(c, err)
+		return
+	}
+	infra.JSONSuccess(c, stats, "Outbox stats")
+}
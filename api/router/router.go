@@ -13,20 +13,39 @@ import (
 	"github.com/olivere/elastic/v7"
 )
 
-func SetupRouter(ctx context.Context, h *server.Hertz, db *sqlx.DB, dbReplica *sqlx.DB, es *elastic.Client) {
+func SetupRouter(ctx context.Context, h *server.Hertz, db *sqlx.DB, dbReplica *sqlx.DB, es *elastic.Client, loginAttempts authors.LoginAttemptStore, mail *authors.AccountMail, verifier *authors.EmailVerifier, keys *middleware.KeySet, outboxRelay *articles.OutboxRelay) {
 	repoAuthors := authors.NewAuthorRepo(db, dbReplica)
 	repoArticles := articles.NewArticleRepo(ctx, db)
 	indexArticles := articles.NewArticleIndexer(es)
 
-	authMiddleware := middleware.AuthMiddleware()
-	svc := service.NewService(ctx, db, repoAuthors, repoArticles, indexArticles)
-	handler := handler.NewAppHandler(svc)
+	denylist := authors.NewTokenDenylist(repoAuthors)
+	authMiddleware := middleware.AuthMiddleware(keys, denylist)
+	optionalAuthMiddleware := middleware.OptionalAuthMiddleware(keys, denylist)
+	adminOnly := middleware.RequireRole(string(authors.RoleAdmin))
+	selfOrAdmin := middleware.RequireSelfOrRole("id", string(authors.RoleAdmin))
+	limiter := authors.NewLoginLimiter(loginAttempts, authors.DefaultEmailPolicy, authors.DefaultIPPolicy)
+	svc := service.NewService(ctx, db, repoAuthors, repoArticles, indexArticles, limiter, mail, verifier, keys, outboxRelay)
+	handler := handler.NewAppHandler(svc, keys)
+
+	h.GET("/.well-known/jwks.json", handler.JWKS)
+	h.GET("/metrics/outbox", authMiddleware, adminOnly, handler.OutboxStats)
 
 	author := h.Group("/author")
 	{
 		author.POST("/login", handler.LoginAuthor)
+		author.POST("/token/refresh", handler.RefreshToken)
+		author.POST("/logout", authMiddleware, handler.Logout)
+		author.POST("/password", authMiddleware, handler.ChangePassword)
+		author.POST("/password/forgot", handler.ForgotPassword)
+		author.POST("/password/reset", handler.ResetPassword)
+		author.GET("/verify", handler.VerifyEmail)
+		author.POST("/verify/resend", authMiddleware, handler.ResendVerification)
 		author.POST("/create", handler.CreateAuthor)
-		author.PUT("/update/:id", authMiddleware, handler.UpdateAuthor)
+		author.PUT("/update/:id", authMiddleware, selfOrAdmin, handler.UpdateAuthor)
+		author.PUT("/:id/role", authMiddleware, adminOnly, handler.SetAuthorRole)
+		author.GET("", handler.ListAuthors)
+		author.GET("/search", handler.SearchAuthors)
+		author.GET("/:id", handler.GetAuthorByID)
 	}
 	article := h.Group("/article")
 	{
@@ -34,8 +53,20 @@ func SetupRouter(ctx context.Context, h *server.Hertz, db *sqlx.DB, dbReplica *s
 		article.POST("/create", authMiddleware, handler.CreateArticle)
 		article.POST("/create-bulk", authMiddleware, handler.CreateManyArticle)
 		article.PUT("/update/:id", authMiddleware, handler.UpdateArticle)
-		article.GET("/search", handler.GetArticleByKeyWord)
+		article.GET("/search", optionalAuthMiddleware, handler.GetArticleByKeyWord)
+		article.GET("/suggest", optionalAuthMiddleware, handler.SuggestArticle)
 		article.GET("/author/:id", authMiddleware, handler.GetArticleWithAuthorByID)
-		article.GET("/author-name", handler.GetArticleByAuthorName)
+		article.GET("/author-name", optionalAuthMiddleware, handler.GetArticleByAuthorName)
+		article.GET("/:id", optionalAuthMiddleware, handler.GetArticleByID)
+		article.DELETE("/:id", authMiddleware, handler.DeleteArticle)
+		article.POST("/:id/restore", authMiddleware, handler.RestoreArticle)
+		article.POST("/:id/publish", authMiddleware, handler.PublishArticle)
+		article.POST("/:id/unpublish", authMiddleware, handler.UnpublishArticle)
+		article.POST("/:id/archive", authMiddleware, handler.ArchiveArticle)
+		article.POST("/:id/schedule", authMiddleware, handler.ScheduleArticle)
+		article.GET("/:id/related", optionalAuthMiddleware, handler.GetRelatedArticles)
+		article.GET("/:id/revisions", authMiddleware, handler.GetArticleRevisions)
+		article.GET("/:id/revisions/:rev", authMiddleware, handler.GetArticleRevision)
+		article.POST("/:id/revisions/:rev/restore", authMiddleware, handler.RestoreArticleRevision)
 	}
 }
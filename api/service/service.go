@@ -2,9 +2,11 @@ package service
 
 import (
 	"context"
+	"time"
 
 	articles "github.com/afif-musyayyidin/hertz-boilerplate/domain/articles"
 	authors "github.com/afif-musyayyidin/hertz-boilerplate/domain/authors"
+	"github.com/afif-musyayyidin/hertz-boilerplate/middleware"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 )
@@ -13,20 +15,30 @@ type Service struct {
 	repoAuthors  authors.AuthorRepository
 	repoArticles articles.ArticleRepository
 	index        articles.ArticleIndexer
+	limiter      *authors.LoginLimiter
+	mail         *authors.AccountMail
+	verifier     *authors.EmailVerifier
+	keys         *middleware.KeySet
+	outboxRelay  *articles.OutboxRelay
 	db           *sqlx.DB
 }
 
-func NewService(ctx context.Context, db *sqlx.DB, repoAuthors authors.AuthorRepository, repoArticles articles.ArticleRepository, index articles.ArticleIndexer) *Service {
+func NewService(ctx context.Context, db *sqlx.DB, repoAuthors authors.AuthorRepository, repoArticles articles.ArticleRepository, index articles.ArticleIndexer, limiter *authors.LoginLimiter, mail *authors.AccountMail, verifier *authors.EmailVerifier, keys *middleware.KeySet, outboxRelay *articles.OutboxRelay) *Service {
 	return &Service{
 		repoAuthors:  repoAuthors,
 		repoArticles: repoArticles,
 		index:        index,
+		limiter:      limiter,
+		mail:         mail,
+		verifier:     verifier,
+		keys:         keys,
+		outboxRelay:  outboxRelay,
 		db:           db,
 	}
 }
 
 func (s *Service) CreateAuthor(ctx context.Context, u authors.AuthorInput) (*uuid.UUID, error) {
-	mutation := authors.NewAuthorMutation(s.repoAuthors, s.db)
+	mutation := authors.NewAuthorMutation(s.repoAuthors, s.db, s.limiter, s.mail, s.verifier, s.keys)
 	id, err := mutation.CreateAuthor(ctx, &u)
 	if err != nil {
 		return nil, err
@@ -34,17 +46,58 @@ func (s *Service) CreateAuthor(ctx context.Context, u authors.AuthorInput) (*uui
 	return id, nil
 }
 
-func (s *Service) UpdateAuthor(ctx context.Context, u authors.AuthorInput, id uuid.UUID) (*uuid.UUID, error) {
-	mutation := authors.NewAuthorMutation(s.repoAuthors, s.db)
-	idResult, err := mutation.UpdateAuthor(ctx, &u, id)
+func (s *Service) UpdateAuthor(ctx context.Context, u authors.AuthorInput, id uuid.UUID, actorID uuid.UUID) (*uuid.UUID, error) {
+	mutation := authors.NewAuthorMutation(s.repoAuthors, s.db, s.limiter, s.mail, s.verifier, s.keys)
+	idResult, err := mutation.UpdateAuthor(ctx, &u, id, actorID)
 	if err != nil {
 		return nil, err
 	}
 	return idResult, nil
 }
 
+func (s *Service) SetAuthorRole(ctx context.Context, id uuid.UUID, role authors.Role, actorID uuid.UUID) error {
+	mutation := authors.NewAuthorMutation(s.repoAuthors, s.db, s.limiter, s.mail, s.verifier, s.keys)
+	return mutation.SetAuthorRole(ctx, id, role, actorID)
+}
+
+func (s *Service) GetAuthorByID(ctx context.Context, id uuid.UUID) (*authors.Author, error) {
+	mutation := authors.NewAuthorMutation(s.repoAuthors, s.db, s.limiter, s.mail, s.verifier, s.keys)
+	author, err := mutation.GetAuthorByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return author, nil
+}
+
+func (s *Service) GetAuthorBySlug(ctx context.Context, slug string) (*authors.Author, error) {
+	mutation := authors.NewAuthorMutation(s.repoAuthors, s.db, s.limiter, s.mail, s.verifier, s.keys)
+	author, err := mutation.GetAuthorBySlug(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	return author, nil
+}
+
+func (s *Service) ListAuthors(ctx context.Context, page authors.PageRequest) (*authors.AuthorPage, error) {
+	mutation := authors.NewAuthorMutation(s.repoAuthors, s.db, s.limiter, s.mail, s.verifier, s.keys)
+	authorPage, err := mutation.ListAuthors(ctx, page)
+	if err != nil {
+		return nil, err
+	}
+	return authorPage, nil
+}
+
+func (s *Service) SearchAuthors(ctx context.Context, name string, page authors.PageRequest) (*authors.AuthorPage, error) {
+	mutation := authors.NewAuthorMutation(s.repoAuthors, s.db, s.limiter, s.mail, s.verifier, s.keys)
+	authorPage, err := mutation.SearchAuthors(ctx, name, page)
+	if err != nil {
+		return nil, err
+	}
+	return authorPage, nil
+}
+
 func (s *Service) CreateArticle(ctx context.Context, u *articles.ArticleInput, authorID uuid.UUID) (*uuid.UUID, error) {
-	mutation := articles.NewArticleMutation(s.repoArticles, s.index, s.db, authors.NewAuthorMutation(s.repoAuthors, s.db))
+	mutation := articles.NewArticleMutation(s.repoArticles, s.index, s.db, authors.NewAuthorMutation(s.repoAuthors, s.db, s.limiter, s.mail, s.verifier, s.keys))
 	idResult, err := mutation.CreateArticle(ctx, u, authorID)
 	if err != nil {
 		return nil, err
@@ -53,7 +106,7 @@ func (s *Service) CreateArticle(ctx context.Context, u *articles.ArticleInput, a
 }
 
 func (s *Service) CreateManyArticle(ctx context.Context, u []*articles.ArticleInput, authorID uuid.UUID) ([]*uuid.UUID, error) {
-	mutation := articles.NewArticleMutation(s.repoArticles, s.index, s.db, authors.NewAuthorMutation(s.repoAuthors, s.db))
+	mutation := articles.NewArticleMutation(s.repoArticles, s.index, s.db, authors.NewAuthorMutation(s.repoAuthors, s.db, s.limiter, s.mail, s.verifier, s.keys))
 	idResult, err := mutation.CreateManyArticle(ctx, u, authorID)
 	if err != nil {
 		return nil, err
@@ -61,56 +114,206 @@ func (s *Service) CreateManyArticle(ctx context.Context, u []*articles.ArticleIn
 	return idResult, nil
 }
 
-func (s *Service) UpdateArticle(ctx context.Context, u *articles.ArticleInput, id uuid.UUID, authorID uuid.UUID) (*uuid.UUID, error) {
-	mutation := articles.NewArticleMutation(s.repoArticles, s.index, s.db, authors.NewAuthorMutation(s.repoAuthors, s.db))
-	idResult, err := mutation.UpdateArticle(ctx, u, id, authorID)
+func (s *Service) UpdateArticle(ctx context.Context, u *articles.ArticleInput, id uuid.UUID, authorID uuid.UUID, version int) (*articles.Article, error) {
+	mutation := articles.NewArticleMutation(s.repoArticles, s.index, s.db, authors.NewAuthorMutation(s.repoAuthors, s.db, s.limiter, s.mail, s.verifier, s.keys))
+	article, err := mutation.UpdateArticle(ctx, u, id, authorID, version)
 	if err != nil {
 		return nil, err
 	}
-	return idResult, nil
+	return article, nil
+}
+
+func (s *Service) GetArticleByKeyWord(ctx context.Context, req articles.SearchRequest, viewerID uuid.UUID, page articles.PageRequest) (*articles.ArticlePage, error) {
+	mutation := articles.NewArticleMutation(s.repoArticles, s.index, s.db, authors.NewAuthorMutation(s.repoAuthors, s.db, s.limiter, s.mail, s.verifier, s.keys))
+	articlePage, err := mutation.GetArticleByKeyWord(ctx, req, viewerID, page)
+	if err != nil {
+		return nil, err
+	}
+	return articlePage, nil
 }
 
-func (s *Service) GetArticleByKeyWord(ctx context.Context, keyword string) ([]*articles.Article, error) {
-	mutation := articles.NewArticleMutation(s.repoArticles, s.index, s.db, authors.NewAuthorMutation(s.repoAuthors, s.db))
-	articleList, err := mutation.GetArticleByKeyWord(ctx, keyword)
+func (s *Service) SuggestArticle(ctx context.Context, prefix string, viewerID uuid.UUID, size int) ([]*articles.Suggestion, error) {
+	mutation := articles.NewArticleMutation(s.repoArticles, s.index, s.db, authors.NewAuthorMutation(s.repoAuthors, s.db, s.limiter, s.mail, s.verifier, s.keys))
+	suggestions, err := mutation.SuggestArticle(ctx, prefix, viewerID, size)
 	if err != nil {
 		return nil, err
 	}
-	return articleList, nil
+	return suggestions, nil
 }
 
-func (s *Service) GetArticleWithAuthorByID(ctx context.Context, id uuid.UUID) (*articles.ArticleWithAuthor, error) {
-	mutation := articles.NewArticleMutation(s.repoArticles, s.index, s.db, authors.NewAuthorMutation(s.repoAuthors, s.db))
-	articleWithAuthor, err := mutation.GetArticleWithAuthorByID(ctx, id)
+func (s *Service) GetArticleByID(ctx context.Context, id uuid.UUID, viewerID uuid.UUID) (*articles.Article, error) {
+	mutation := articles.NewArticleMutation(s.repoArticles, s.index, s.db, authors.NewAuthorMutation(s.repoAuthors, s.db, s.limiter, s.mail, s.verifier, s.keys))
+	article, err := mutation.GetArticleByID(ctx, id, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	return article, nil
+}
+
+func (s *Service) GetRelatedArticles(ctx context.Context, id uuid.UUID, viewerID uuid.UUID, size int) ([]*articles.Article, error) {
+	mutation := articles.NewArticleMutation(s.repoArticles, s.index, s.db, authors.NewAuthorMutation(s.repoAuthors, s.db, s.limiter, s.mail, s.verifier, s.keys))
+	related, err := mutation.GetRelatedArticles(ctx, id, viewerID, size)
+	if err != nil {
+		return nil, err
+	}
+	return related, nil
+}
+
+func (s *Service) GetArticleWithAuthorByID(ctx context.Context, id uuid.UUID, viewerID uuid.UUID, page articles.PageRequest) (*articles.ArticleWithAuthor, error) {
+	mutation := articles.NewArticleMutation(s.repoArticles, s.index, s.db, authors.NewAuthorMutation(s.repoAuthors, s.db, s.limiter, s.mail, s.verifier, s.keys))
+	articleWithAuthor, err := mutation.GetArticleWithAuthorByID(ctx, id, viewerID, page)
 	if err != nil {
 		return nil, err
 	}
 	return articleWithAuthor, nil
 }
 
-func (s *Service) GetArticleByAuthorName(ctx context.Context, name string) ([]*articles.ArticleWithAuthor, error) {
-	mutation := articles.NewArticleMutation(s.repoArticles, s.index, s.db, authors.NewAuthorMutation(s.repoAuthors, s.db))
-	articleWithAuthorList, err := mutation.GetArticleByAuthorName(ctx, name)
+func (s *Service) GetArticleByAuthorName(ctx context.Context, name string, viewerID uuid.UUID, page articles.PageRequest) (*articles.ArticleWithAuthorPage, error) {
+	mutation := articles.NewArticleMutation(s.repoArticles, s.index, s.db, authors.NewAuthorMutation(s.repoAuthors, s.db, s.limiter, s.mail, s.verifier, s.keys))
+	articleWithAuthorPage, err := mutation.GetArticleByAuthorName(ctx, name, viewerID, page)
 	if err != nil {
 		return nil, err
 	}
-	return articleWithAuthorList, nil
+	return articleWithAuthorPage, nil
 }
 
-func (s *Service) LoginAuthor(ctx context.Context, email string, password string) (*string, error) {
-	mutation := authors.NewAuthorMutation(s.repoAuthors, s.db)
-	token, err := mutation.LoginAuthor(ctx, email, password)
+func (s *Service) LoginAuthor(ctx context.Context, email string, password string, ip string) (*authors.TokenPair, error) {
+	mutation := authors.NewAuthorMutation(s.repoAuthors, s.db, s.limiter, s.mail, s.verifier, s.keys)
+	token, err := mutation.LoginAuthor(ctx, email, password, ip)
 	if err != nil {
 		return nil, err
 	}
 	return token, nil
 }
 
-func (s *Service) GetAllArticle(ctx context.Context) ([]*articles.Article, error) {
-	mutation := articles.NewArticleMutation(s.repoArticles, s.index, s.db, authors.NewAuthorMutation(s.repoAuthors, s.db))
-	articleList, err := mutation.GetAllArticle(ctx)
+func (s *Service) RefreshToken(ctx context.Context, refreshToken string) (*authors.TokenPair, error) {
+	mutation := authors.NewAuthorMutation(s.repoAuthors, s.db, s.limiter, s.mail, s.verifier, s.keys)
+	token, err := mutation.RefreshToken(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (s *Service) Logout(ctx context.Context, authorID uuid.UUID, tokenID uuid.UUID, expiresAt time.Time) error {
+	mutation := authors.NewAuthorMutation(s.repoAuthors, s.db, s.limiter, s.mail, s.verifier, s.keys)
+	return mutation.Logout(ctx, authorID, tokenID, expiresAt)
+}
+
+func (s *Service) ChangePassword(ctx context.Context, authorID uuid.UUID, currentPassword string, newPassword string, ip string) (*authors.TokenPair, error) {
+	mutation := authors.NewAuthorMutation(s.repoAuthors, s.db, s.limiter, s.mail, s.verifier, s.keys)
+	token, err := mutation.ChangePassword(ctx, authorID, currentPassword, newPassword, ip)
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (s *Service) ForgotPassword(ctx context.Context, email string) error {
+	mutation := authors.NewAuthorMutation(s.repoAuthors, s.db, s.limiter, s.mail, s.verifier, s.keys)
+	return mutation.ForgotPassword(ctx, email)
+}
+
+func (s *Service) ResetPassword(ctx context.Context, token string, newPassword string) error {
+	mutation := authors.NewAuthorMutation(s.repoAuthors, s.db, s.limiter, s.mail, s.verifier, s.keys)
+	return mutation.ResetPassword(ctx, token, newPassword)
+}
+
+func (s *Service) VerifyEmail(ctx context.Context, token string) error {
+	mutation := authors.NewAuthorMutation(s.repoAuthors, s.db, s.limiter, s.mail, s.verifier, s.keys)
+	return mutation.VerifyEmail(ctx, token)
+}
+
+func (s *Service) ResendVerification(ctx context.Context, authorID uuid.UUID) error {
+	mutation := authors.NewAuthorMutation(s.repoAuthors, s.db, s.limiter, s.mail, s.verifier, s.keys)
+	return mutation.ResendVerification(ctx, authorID)
+}
+
+func (s *Service) GetAllArticle(ctx context.Context, viewerID uuid.UUID, page articles.PageRequest) (*articles.ArticlePage, error) {
+	mutation := articles.NewArticleMutation(s.repoArticles, s.index, s.db, authors.NewAuthorMutation(s.repoAuthors, s.db, s.limiter, s.mail, s.verifier, s.keys))
+	articlePage, err := mutation.GetAllArticle(ctx, viewerID, page)
+	if err != nil {
+		return nil, err
+	}
+	return articlePage, nil
+}
+func (s *Service) DeleteArticle(ctx context.Context, id uuid.UUID, authorID uuid.UUID) error {
+	mutation := articles.NewArticleMutation(s.repoArticles, s.index, s.db, authors.NewAuthorMutation(s.repoAuthors, s.db, s.limiter, s.mail, s.verifier, s.keys))
+	return mutation.DeleteArticle(ctx, id, authorID)
+}
+
+func (s *Service) RestoreArticle(ctx context.Context, id uuid.UUID, authorID uuid.UUID) (*uuid.UUID, error) {
+	mutation := articles.NewArticleMutation(s.repoArticles, s.index, s.db, authors.NewAuthorMutation(s.repoAuthors, s.db, s.limiter, s.mail, s.verifier, s.keys))
+	idResult, err := mutation.RestoreArticle(ctx, id, authorID)
+	if err != nil {
+		return nil, err
+	}
+	return idResult, nil
+}
+
+func (s *Service) PublishArticle(ctx context.Context, id uuid.UUID, authorID uuid.UUID) (*uuid.UUID, error) {
+	mutation := articles.NewArticleMutation(s.repoArticles, s.index, s.db, authors.NewAuthorMutation(s.repoAuthors, s.db, s.limiter, s.mail, s.verifier, s.keys))
+	idResult, err := mutation.PublishArticle(ctx, id, authorID)
+	if err != nil {
+		return nil, err
+	}
+	return idResult, nil
+}
+
+func (s *Service) UnpublishArticle(ctx context.Context, id uuid.UUID, authorID uuid.UUID) (*uuid.UUID, error) {
+	mutation := articles.NewArticleMutation(s.repoArticles, s.index, s.db, authors.NewAuthorMutation(s.repoAuthors, s.db, s.limiter, s.mail, s.verifier, s.keys))
+	idResult, err := mutation.UnpublishArticle(ctx, id, authorID)
 	if err != nil {
 		return nil, err
 	}
-	return articleList, nil
-}
\ No newline at end of file
+	return idResult, nil
+}
+
+func (s *Service) ArchiveArticle(ctx context.Context, id uuid.UUID, authorID uuid.UUID) (*uuid.UUID, error) {
+	mutation := articles.NewArticleMutation(s.repoArticles, s.index, s.db, authors.NewAuthorMutation(s.repoAuthors, s.db, s.limiter, s.mail, s.verifier, s.keys))
+	idResult, err := mutation.ArchiveArticle(ctx, id, authorID)
+	if err != nil {
+		return nil, err
+	}
+	return idResult, nil
+}
+
+func (s *Service) ScheduleArticle(ctx context.Context, id uuid.UUID, authorID uuid.UUID, publishAt time.Time) (*uuid.UUID, error) {
+	mutation := articles.NewArticleMutation(s.repoArticles, s.index, s.db, authors.NewAuthorMutation(s.repoAuthors, s.db, s.limiter, s.mail, s.verifier, s.keys))
+	idResult, err := mutation.ScheduleArticle(ctx, id, authorID, publishAt)
+	if err != nil {
+		return nil, err
+	}
+	return idResult, nil
+}
+
+func (s *Service) GetArticleRevisions(ctx context.Context, id uuid.UUID, authorID uuid.UUID) ([]*articles.Revision, error) {
+	mutation := articles.NewArticleMutation(s.repoArticles, s.index, s.db, authors.NewAuthorMutation(s.repoAuthors, s.db, s.limiter, s.mail, s.verifier, s.keys))
+	revisions, err := mutation.GetArticleRevisions(ctx, id, authorID)
+	if err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+func (s *Service) GetArticleRevision(ctx context.Context, id uuid.UUID, revision int, authorID uuid.UUID) (*articles.RevisionDiff, error) {
+	mutation := articles.NewArticleMutation(s.repoArticles, s.index, s.db, authors.NewAuthorMutation(s.repoAuthors, s.db, s.limiter, s.mail, s.verifier, s.keys))
+	revisionDiff, err := mutation.GetArticleRevision(ctx, id, revision, authorID)
+	if err != nil {
+		return nil, err
+	}
+	return revisionDiff, nil
+}
+
+func (s *Service) RestoreArticleRevision(ctx context.Context, id uuid.UUID, revision int, authorID uuid.UUID) (*uuid.UUID, error) {
+	mutation := articles.NewArticleMutation(s.repoArticles, s.index, s.db, authors.NewAuthorMutation(s.repoAuthors, s.db, s.limiter, s.mail, s.verifier, s.keys))
+	idResult, err := mutation.RestoreArticleRevision(ctx, id, revision, authorID)
+	if err != nil {
+		return nil, err
+	}
+	return idResult, nil
+}
+
+func (s *Service) OutboxStats(ctx context.Context) (*articles.OutboxStats, error) {
+	return s.outboxRelay.Stats(ctx)
+}
@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 type Config struct {
 	AppName       string `envconfig:"APP_NAME" default:"HertzApp"`
 	Port          int    `envconfig:"PORT" default:"8080"`
@@ -8,8 +10,59 @@ type Config struct {
 	DBUser        string `envconfig:"DB_USER" required:"true"`
 	DBPassword    string `envconfig:"DB_PASSWORD" required:"true"`
 	DBName        string `envconfig:"DB_NAME" required:"true"`
-	JWTSecret     string `envconfig:"JWT_SECRET" required:"true"`
 	DBReplicaHost string `envconfig:"DB_REPLICA_HOST" required:"true"`
 	DBReplicaPort int    `envconfig:"DB_REPLICA_PORT" default:"5433"`
 	ElasticURL    string `envconfig:"ELASTIC_URL" required:"true" default:"http://localhost:9200"`
+
+	// AppEnv is "production" or "development". Only development may run
+	// without signing secrets, using temporary ones.
+	AppEnv string `envconfig:"APP_ENV" default:"production"`
+
+	ArticlePurgeInterval  time.Duration `envconfig:"ARTICLE_PURGE_INTERVAL" default:"1h"`
+	ArticlePurgeRetention time.Duration `envconfig:"ARTICLE_PURGE_RETENTION" default:"720h"`
+
+	PublishSchedulerInterval  time.Duration `envconfig:"PUBLISH_SCHEDULER_INTERVAL" default:"30s"`
+	PublishSchedulerBatchSize int           `envconfig:"PUBLISH_SCHEDULER_BATCH_SIZE" default:"100"`
+
+	OutboxRelayInterval  time.Duration `envconfig:"OUTBOX_RELAY_INTERVAL" default:"1s"`
+	OutboxRelayBatchSize int           `envconfig:"OUTBOX_RELAY_BATCH_SIZE" default:"100"`
+	OutboxMaxAttempts    int           `envconfig:"OUTBOX_MAX_ATTEMPTS" default:"10"`
+
+	ReindexBatchSize int `envconfig:"REINDEX_BATCH_SIZE" default:"500"`
+
+	// LoginAttemptStore is where failed logins are counted: "postgres", shared
+	// by all instances, or "memory" for a single instance.
+	LoginAttemptStore string `envconfig:"LOGIN_ATTEMPT_STORE" default:"postgres"`
+	// TrustedProxies are the CIDRs of the load balancers allowed to name the
+	// client in X-Forwarded-For, e.g. "10.0.0.0/8". Requests from anywhere
+	// else are known by their remote address.
+	TrustedProxies []string `envconfig:"TRUSTED_PROXIES"`
+
+	// Mailer is "smtp" or "file"; the file mailer writes to MailDir, or to the
+	// log when MailDir is empty.
+	Mailer       string `envconfig:"MAILER" default:"file"`
+	MailDir      string `envconfig:"MAIL_DIR"`
+	MailFrom     string `envconfig:"MAIL_FROM" default:"no-reply@localhost"`
+	SMTPHost     string `envconfig:"SMTP_HOST" default:"localhost"`
+	SMTPPort     int    `envconfig:"SMTP_PORT" default:"587"`
+	SMTPUsername string `envconfig:"SMTP_USERNAME"`
+	SMTPPassword string `envconfig:"SMTP_PASSWORD"`
+
+	// PasswordResetURL is the page that receives the reset token as ?token=.
+	PasswordResetURL string `envconfig:"PASSWORD_RESET_URL" default:"http://localhost:3000/reset-password"`
+
+	// EmailVerificationSecret signs the email verification links, at least 32
+	// bytes. Only development may leave it out and use a temporary secret.
+	EmailVerificationSecret string `envconfig:"EMAIL_VERIFICATION_SECRET"`
+	EmailVerificationURL    string `envconfig:"EMAIL_VERIFICATION_URL" default:"http://localhost:8080/author/verify"`
+
+	JWTSigningKeyFile string `envconfig:"JWT_SIGNING_KEY_FILE"`
+	JWTSigningKeyID   string `envconfig:"JWT_SIGNING_KEY_ID" default:"default"`
+	// JWTVerificationKeys maps a kid to a PEM public key file, e.g.
+	// "2025-09:/keys/2025-09.pub,2025-10:/keys/2025-10.pub".
+	JWTVerificationKeys map[string]string `envconfig:"JWT_VERIFICATION_KEYS"`
+}
+
+func (c Config) Development() bool {
+	return c.AppEnv == "development"
 }
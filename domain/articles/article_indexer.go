@@ -3,18 +3,30 @@ package articles
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
 
+	"github.com/afif-musyayyidin/hertz-boilerplate/domain/infra/logger"
 	"github.com/google/uuid"
 	"github.com/olivere/elastic/v7"
 )
 
 type ArticleIndexer interface {
 	Index(ctx context.Context, a *Article) error
-	Search(ctx context.Context, keyword string) ([]*Article, error)
-	GetAllArticle(ctx context.Context) ([]*Article, error)
-	GetArticleByAuthorID(ctx context.Context, authorID uuid.UUID) ([]*Article, error)
-	GetArticleByAuthorIDList(ctx context.Context, authorIDList []uuid.UUID) ([]*Article, error)
-	UpdateField(ctx context.Context, id string, fields map[string]interface{}) error
+	Search(ctx context.Context, req SearchRequest, viewerID uuid.UUID, page PageRequest) (*ArticlePage, error)
+	Suggest(ctx context.Context, prefix string, viewerID uuid.UUID, size int) ([]*Suggestion, error)
+	GetRelatedArticles(ctx context.Context, a *Article, size int) ([]*Article, error)
+	GetAllArticle(ctx context.Context, viewerID uuid.UUID, page PageRequest) (*ArticlePage, error)
+	GetArticleByAuthorID(ctx context.Context, authorID uuid.UUID, viewerID uuid.UUID, page PageRequest) (*ArticlePage, error)
+	GetArticleByAuthorIDList(ctx context.Context, authorIDList []uuid.UUID, viewerID uuid.UUID, page PageRequest) (*ArticlePage, error)
+	Delete(ctx context.Context, id string) error
+	EnsureIndex(ctx context.Context) error
+	CreateVersionedIndex(ctx context.Context) (string, error)
+	IndexExists(ctx context.Context, name string) (bool, error)
+	BulkIndex(ctx context.Context, index string, articles []*Article) error
+	SwapAlias(ctx context.Context, index string) error
 }
 
 type articleIndexer struct {
@@ -25,110 +37,407 @@ func NewArticleIndexer(es *elastic.Client) ArticleIndexer {
 	return &articleIndexer{es: es}
 }
 
+// Index writes the whole document using the article version as the ES
+// external version, so a write carrying an older version than the indexed one
+// is rejected by ES.
 func (i *articleIndexer) Index(ctx context.Context, a *Article) error {
 	_, err := i.es.Index().
-		Index("articles").
+		Index(ArticleIndexAlias).
 		Id(a.ID.String()).
+		Version(a.Version).
+		VersionType("external").
 		BodyJson(a).
 		Do(ctx)
+	if elastic.IsConflict(err) {
+		// The index already holds this version or a newer one.
+		logger.Debug("skipping stale index write", map[string]interface{}{"id": a.ID, "version": a.Version})
+		return nil
+	}
 	return err
 }
 
-func (i *articleIndexer) GetAllArticle(ctx context.Context) ([]*Article, error) {
-	searchResult, err := i.es.Search().
-		Index("articles").
+// ArticleIndexAlias is the name every read and write goes through. It points
+// at exactly one articles_v{N} index, which the Reindexer swaps atomically.
+const ArticleIndexAlias = "articles"
+
+const articleIndexPrefix = ArticleIndexAlias + "_v"
+
+// articleMapping is the complete mapping of article documents. Dynamic
+// mapping is off: fields missing here are kept in _source but not indexed.
+// title and body are also analyzed with the Indonesian and English stemmers,
+// so "artikel"/"artikelnya" and "write"/"writing" match each other.
+// title.suggest is indexed for search-as-you-type.
+const articleMapping = `{
+	"dynamic": false,
+	"properties": {
+		"id": { "type": "keyword" },
+		"title": {
+			"type": "text",
+			"fields": {
+				"id": { "type": "text", "analyzer": "indonesian" },
+				"en": { "type": "text", "analyzer": "english" },
+				"suggest": { "type": "search_as_you_type" }
+			}
+		},
+		"body": {
+			"type": "text",
+			"fields": {
+				"id": { "type": "text", "analyzer": "indonesian" },
+				"en": { "type": "text", "analyzer": "english" }
+			}
+		},
+		"author_id": { "type": "keyword" },
+		"status": { "type": "keyword" },
+		"publish_at": { "type": "date" },
+		"version": { "type": "integer" },
+		"created_at": { "type": "date" },
+		"updated_at": { "type": "date" },
+		"author": {
+			"properties": {
+				"id": { "type": "keyword" },
+				"name": { "type": "text", "fields": { "keyword": { "type": "keyword" } } },
+				"slug": { "type": "keyword" },
+				"avatar_url": { "type": "keyword", "index": false }
+			}
+		}
+	}
+}`
+
+// EnsureIndex makes sure the articles alias exists. On an empty cluster it
+// creates the first versioned index behind the alias; an index created before
+// the alias existed must be migrated with the reindex command.
+func (i *articleIndexer) EnsureIndex(ctx context.Context) error {
+	aliases, err := i.es.Aliases().Index("_all").Do(ctx)
+	if err != nil {
+		return err
+	}
+	if len(aliases.IndicesByAlias(ArticleIndexAlias)) > 0 {
+		_, err := i.es.PutMapping().
+			Index(ArticleIndexAlias).
+			BodyString(articleMapping).
+			Do(ctx)
+		return err
+	}
+	legacy, err := i.es.IndexExists(ArticleIndexAlias).Do(ctx)
+	if err != nil {
+		return err
+	}
+	if legacy {
+		return fmt.Errorf("index %q is not an alias, run the reindex command", ArticleIndexAlias)
+	}
+	name, err := i.CreateVersionedIndex(ctx)
+	if err != nil {
+		return err
+	}
+	return i.SwapAlias(ctx, name)
+}
+
+// CreateVersionedIndex creates articles_v{N+1}, where N is the highest
+// existing version, and returns its name.
+func (i *articleIndexer) CreateVersionedIndex(ctx context.Context) (string, error) {
+	indices, err := i.es.IndexGet(articleIndexPrefix + "*").Do(ctx)
+	if err != nil {
+		return "", err
+	}
+	latest := 0
+	for name := range indices {
+		if version, err := strconv.Atoi(strings.TrimPrefix(name, articleIndexPrefix)); err == nil && version > latest {
+			latest = version
+		}
+	}
+	name := articleIndexPrefix + strconv.Itoa(latest+1)
+	_, err = i.es.CreateIndex(name).
+		BodyString(`{"mappings": ` + articleMapping + `}`).
 		Do(ctx)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-	results := make([]*Article, 0, len(searchResult.Hits.Hits))
-	for _, hit := range searchResult.Hits.Hits {
-		var a Article
-		if err := json.Unmarshal(hit.Source, &a); err != nil {
+	return name, nil
+}
+
+func (i *articleIndexer) IndexExists(ctx context.Context, name string) (bool, error) {
+	return i.es.IndexExists(name).Do(ctx)
+}
+
+// BulkIndex writes articles straight into index, which does not have to be
+// behind the alias yet. Deleted articles are removed. Writes use external_gte
+// versioning: documents already at a newer version are kept, while documents
+// at the same version are overwritten, which refreshes the denormalized
+// author of an unchanged article.
+func (i *articleIndexer) BulkIndex(ctx context.Context, index string, articles []*Article) error {
+	if len(articles) == 0 {
+		return nil
+	}
+	bulk := i.es.Bulk().Index(index)
+	for _, a := range articles {
+		if a.DeletedAt != nil {
+			bulk.Add(elastic.NewBulkDeleteRequest().
+				Id(a.ID.String()).
+				Version(int64(a.Version)).
+				VersionType("external_gte"))
 			continue
 		}
-		results = append(results, &a)
+		bulk.Add(elastic.NewBulkIndexRequest().
+			Id(a.ID.String()).
+			Version(int64(a.Version)).
+			VersionType("external_gte").
+			Doc(a))
+	}
+	response, err := bulk.Do(ctx)
+	if err != nil {
+		return err
+	}
+	for _, item := range response.Failed() {
+		if item.Status == http.StatusConflict || item.Status == http.StatusNotFound {
+			continue
+		}
+		return fmt.Errorf("bulk index of article %s failed: %s", item.Id, item.Error.Reason)
+	}
+	return nil
+}
+
+// SwapAlias points the articles alias at index and away from every other
+// index in one atomic request. A legacy index named like the alias is
+// deleted in the same request.
+func (i *articleIndexer) SwapAlias(ctx context.Context, index string) error {
+	aliases, err := i.es.Aliases().Index("_all").Do(ctx)
+	if err != nil {
+		return err
+	}
+	current := aliases.IndicesByAlias(ArticleIndexAlias)
+	swap := i.es.Alias()
+	for _, name := range current {
+		if name != index {
+			swap = swap.Remove(name, ArticleIndexAlias)
+		}
+	}
+	if len(current) == 0 {
+		legacy, err := i.es.IndexExists(ArticleIndexAlias).Do(ctx)
+		if err != nil {
+			return err
+		}
+		if legacy {
+			swap = swap.Action(elastic.NewAliasRemoveIndexAction(ArticleIndexAlias))
+		}
+	}
+	_, err = swap.Add(index, ArticleIndexAlias).Do(ctx)
+	return err
+}
+
+func (i *articleIndexer) GetAllArticle(ctx context.Context, viewerID uuid.UUID, page PageRequest) (*ArticlePage, error) {
+	return i.searchPage(ctx, elastic.NewMatchAllQuery(), newestFirst, viewerID, page, nil)
+}
+
+func (i *articleIndexer) GetArticleByAuthorID(ctx context.Context, authorID uuid.UUID, viewerID uuid.UUID, page PageRequest) (*ArticlePage, error) {
+	query := elastic.NewTermQuery("author_id", authorID.String())
+	return i.searchPage(ctx, query, newestFirst, viewerID, page, nil)
+}
+
+func (i *articleIndexer) Search(ctx context.Context, req SearchRequest, viewerID uuid.UUID, page PageRequest) (*ArticlePage, error) {
+	filters, err := req.filters()
+	if err != nil {
+		return nil, err
+	}
+	order := bestMatchFirst
+	query := i.buildArticleSearchQuery(req.Keyword)
+	if strings.TrimSpace(req.Keyword) == "" {
+		order = newestFirst
+		query = elastic.NewBoolQuery().Must(elastic.NewMatchAllQuery())
 	}
-	return results, nil
+	query.Filter(filters...)
+	return i.searchPage(ctx, query, order, viewerID, page, &req)
 }
 
-func (i *articleIndexer) GetArticleByAuthorID(ctx context.Context, authorID uuid.UUID) ([]*Article, error) {
-	query := elastic.NewTermQuery("author_id.keyword", authorID.String())
+// Suggest returns the articles whose title has words starting with the
+// typed words, the last one possibly incomplete. Scores are relative to the
+// best match, which scores 1.
+func (i *articleIndexer) Suggest(ctx context.Context, prefix string, viewerID uuid.UUID, size int) ([]*Suggestion, error) {
+	query := elastic.NewMultiMatchQuery(prefix,
+		"title.suggest",
+		"title.suggest._2gram",
+		"title.suggest._3gram",
+	).Type("bool_prefix")
 	searchResult, err := i.es.Search().
-		Index("articles").
-		Query(query).
-		Sort("created_at", false).
+		Index(ArticleIndexAlias).
+		Query(elastic.NewBoolQuery().Must(query).Filter(i.visibilityQuery(viewerID))).
+		FetchSourceContext(elastic.NewFetchSourceContext(true).Include("id", "title")).
+		TrackTotalHits(false).
+		Size(size).
 		Do(ctx)
 	if err != nil {
 		return nil, err
 	}
-	results := make([]*Article, 0, len(searchResult.Hits.Hits))
+
+	suggestions := make([]*Suggestion, 0, len(searchResult.Hits.Hits))
 	for _, hit := range searchResult.Hits.Hits {
 		var a Article
 		if err := json.Unmarshal(hit.Source, &a); err != nil {
 			continue
 		}
-		results = append(results, &a)
+		suggestion := &Suggestion{Type: SuggestionArticle, ID: a.ID, Text: a.Title}
+		if hit.Score != nil && searchResult.Hits.MaxScore != nil && *searchResult.Hits.MaxScore > 0 {
+			suggestion.Score = *hit.Score / *searchResult.Hits.MaxScore
+		}
+		suggestions = append(suggestions, suggestion)
 	}
-	return results, nil
+	return suggestions, nil
 }
 
-func (i *articleIndexer) Search(ctx context.Context, keyword string) ([]*Article, error) {
-
-	query := i.buildArticleWildcardQuery(keyword)
-
+// GetRelatedArticles returns published articles whose title and body share
+// the most distinctive terms with a. The text of a is sent as is, so it does
+// not have to be indexed yet; a itself is never returned.
+func (i *articleIndexer) GetRelatedArticles(ctx context.Context, a *Article, size int) ([]*Article, error) {
+	query := elastic.NewMoreLikeThisQuery().
+		Field("title", "body").
+		LikeText(a.Title + "\n" + a.Body).
+		MinTermFreq(1).
+		MinDocFreq(1).
+		MaxQueryTerms(25).
+		MinimumShouldMatch("30%")
 	searchResult, err := i.es.Search().
-		Index("articles").
-		Query(query).
-		Sort("created_at", false).
+		Index(ArticleIndexAlias).
+		Query(elastic.NewBoolQuery().
+			Must(query).
+			Filter(elastic.NewTermQuery("status", string(StatusPublished))).
+			MustNot(elastic.NewIdsQuery().Ids(a.ID.String()))).
+		TrackTotalHits(false).
+		Size(size).
 		Do(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	results := make([]*Article, 0, len(searchResult.Hits.Hits))
+	related := make([]*Article, 0, len(searchResult.Hits.Hits))
 	for _, hit := range searchResult.Hits.Hits {
-		var a Article
-		if err := json.Unmarshal(hit.Source, &a); err != nil {
+		var article Article
+		if err := json.Unmarshal(hit.Source, &article); err != nil {
 			continue
 		}
-		results = append(results, &a)
+		article.Score = hit.Score
+		related = append(related, &article)
 	}
-
-	return results, nil
+	return related, nil
 }
 
-func (i *articleIndexer) UpdateField(ctx context.Context, id string, fields map[string]interface{}) error {
-	_, err := i.es.Update().
-		Index("articles").
+// Delete removes the document from the index. A document that is already
+// gone is not an error.
+func (i *articleIndexer) Delete(ctx context.Context, id string) error {
+	_, err := i.es.Delete().
+		Index(ArticleIndexAlias).
 		Id(id).
-		Doc(fields).
-		DocAsUpsert(true).
 		Do(ctx)
-	return err
+	if err != nil && !elastic.IsNotFound(err) {
+		return err
+	}
+	return nil
 }
 
-func (i *articleIndexer) GetArticleByAuthorIDList(ctx context.Context, authorIDList []uuid.UUID) ([]*Article, error) {
-	query := elastic.NewTermsQuery("author_id.keyword", i.ChangeUIDtoInterface(authorIDList)...)
+func (i *articleIndexer) GetArticleByAuthorIDList(ctx context.Context, authorIDList []uuid.UUID, viewerID uuid.UUID, page PageRequest) (*ArticlePage, error) {
+	query := elastic.NewTermsQuery("author_id", i.ChangeUIDtoInterface(authorIDList)...)
+	return i.searchPage(ctx, query, newestFirst, viewerID, page, nil)
+}
 
-	searchResult, err := i.es.Search().
-		Index("articles").
-		Query(query).
-		Sort("created_at", false).
-		Do(ctx)
+// pageOrder is a sort order for searchPage. Its name goes into the cursor,
+// so a cursor is only accepted by a listing sorted the same way.
+type pageOrder struct {
+	name    string
+	sorters []elastic.Sorter
+}
+
+// Sort orders for searchPage. Both end with id, so every hit has a unique
+// sort key to continue from with search_after.
+var (
+	newestFirst = pageOrder{
+		name: "newest",
+		sorters: []elastic.Sorter{
+			elastic.NewFieldSort("created_at").Desc(),
+			elastic.NewFieldSort("id").Desc(),
+		},
+	}
+	bestMatchFirst = pageOrder{
+		name:    "best",
+		sorters: append([]elastic.Sorter{elastic.NewScoreSort()}, newestFirst.sorters...),
+	}
+)
+
+// searchPage runs query, restricted to what viewerID may see, sorted by order
+// and pages through it with search_after. One extra hit is fetched to know
+// whether a next page exists. req is only given for searches and adds field
+// selection, highlighting and facets.
+func (i *articleIndexer) searchPage(ctx context.Context, query elastic.Query, order pageOrder, viewerID uuid.UUID, page PageRequest, req *SearchRequest) (*ArticlePage, error) {
+	searchAfter, err := page.searchAfter(order)
 	if err != nil {
 		return nil, err
 	}
+	size := page.size()
 
-	results := make([]*Article, 0, len(searchResult.Hits.Hits))
-	for _, hit := range searchResult.Hits.Hits {
+	search := i.es.Search().
+		Index(ArticleIndexAlias).
+		Query(elastic.NewBoolQuery().Must(query).Filter(i.visibilityQuery(viewerID))).
+		SortBy(order.sorters...).
+		Size(size + 1)
+	if searchAfter != nil {
+		search = search.SearchAfter(searchAfter...)
+	}
+	if req != nil {
+		includes, err := req.includes()
+		if err != nil {
+			return nil, err
+		}
+		if includes != nil {
+			search = search.FetchSourceContext(elastic.NewFetchSourceContext(true).Include(includes...))
+		}
+		highlight, err := i.buildHighlight(req.Highlight)
+		if err != nil {
+			return nil, err
+		}
+		search = search.Highlight(highlight)
+		for name, aggregation := range req.aggregations() {
+			search = search.Aggregation(name, aggregation)
+		}
+	}
+	searchResult, err := search.Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := searchResult.Hits.Hits
+	result := &ArticlePage{Articles: make([]*Article, 0, len(hits))}
+	if len(hits) > size {
+		hits = hits[:size]
+		result.NextCursor = encodeCursor(order, hits[len(hits)-1].Sort)
+	}
+	for _, hit := range hits {
 		var a Article
 		if err := json.Unmarshal(hit.Source, &a); err != nil {
 			continue
 		}
-		results = append(results, &a)
+		a.Score = hit.Score
+		if len(hit.Highlight) > 0 {
+			a.Highlights = hit.Highlight
+			if body := hit.Highlight["body"]; len(body) > 0 {
+				a.Snippet = body[0]
+			}
+		}
+		result.Articles = append(result.Articles, &a)
+	}
+	if req != nil {
+		result.Facets = parseFacets(searchResult.Aggregations)
+	}
+	return result, nil
+}
+
+// visibilityQuery matches published articles, plus every article of viewerID
+// when the caller is logged in. uuid.Nil means an anonymous caller.
+func (i *articleIndexer) visibilityQuery(viewerID uuid.UUID) elastic.Query {
+	published := elastic.NewTermQuery("status", string(StatusPublished))
+	if viewerID == uuid.Nil {
+		return published
 	}
-	return results, nil
+	return elastic.NewBoolQuery().Should(
+		published,
+		elastic.NewTermQuery("author_id", viewerID.String()),
+	)
 }
 
 func (i *articleIndexer) ChangeUIDtoInterface(arr []uuid.UUID) []interface{} {
@@ -139,13 +448,67 @@ func (i *articleIndexer) ChangeUIDtoInterface(arr []uuid.UUID) []interface{} {
 	return res
 }
 
-func (i *articleIndexer) buildArticleWildcardQuery(keyword string) *elastic.BoolQuery {
-	likeKeyword := "*" + keyword + "*"
+// buildHighlight highlights the whole title and the best body fragments.
+// The body always yields one fragment, the start of the body when nothing in
+// it matched, which is used as the snippet. Highlighted text is HTML-escaped
+// apart from the tags.
+func (i *articleIndexer) buildHighlight(opts HighlightOptions) (*elastic.Highlight, error) {
+	preTag, postTag, err := opts.tags()
+	if err != nil {
+		return nil, err
+	}
+	fragmentSize := opts.fragmentSize()
+	return elastic.NewHighlight().
+		Encoder("html").
+		PreTags(preTag).
+		PostTags(postTag).
+		RequireFieldMatch(false).
+		Fields(
+			elastic.NewHighlighterField("title").NumOfFragments(0),
+			elastic.NewHighlighterField("body").
+				FragmentSize(fragmentSize).
+				NumOfFragments(3).
+				NoMatchSize(fragmentSize),
+		), nil
+}
+
+// searchFields are the fields a keyword is matched against; a title match
+// weighs three times a body match.
+var searchFields = []string{
+	"title^3", "title.id^3", "title.en^3",
+	"body", "body.id", "body.en",
+}
 
-	query := elastic.NewBoolQuery().
-		Should(
-			elastic.NewWildcardQuery("title", likeKeyword),
-			elastic.NewWildcardQuery("body", likeKeyword),
-		)
+// buildArticleSearchQuery turns the keyword into a relevance query. Words
+// are matched with typo tolerance, most of them have to match, and articles
+// containing them as an exact phrase rank higher. Parts of the keyword in
+// double quotes must appear as an exact phrase.
+func (i *articleIndexer) buildArticleSearchQuery(keyword string) *elastic.BoolQuery {
+	query := elastic.NewBoolQuery()
+	var words []string
+	for n, part := range strings.Split(keyword, `"`) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		// odd parts sit between a pair of quotes
+		if n%2 == 1 {
+			query.Must(elastic.NewMultiMatchQuery(part, searchFields...).Type("phrase"))
+			continue
+		}
+		words = append(words, part)
+	}
+	if len(words) > 0 {
+		text := strings.Join(words, " ")
+		query.Must(elastic.NewMultiMatchQuery(text, searchFields...).
+			Type("most_fields").
+			Fuzziness("AUTO").
+			PrefixLength(1).
+			MinimumShouldMatch("2<75%"))
+		query.Should(elastic.NewMultiMatchQuery(text, searchFields...).
+			Type("phrase").
+			Slop(2).
+			Boost(2))
+	}
 	return query
 }
@@ -2,7 +2,12 @@ package articles
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"sort"
+	"time"
 
+	"github.com/afif-musyayyidin/hertz-boilerplate/domain/authors"
 	"github.com/afif-musyayyidin/hertz-boilerplate/domain/infra/logger"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
@@ -32,16 +37,31 @@ func (a *ArticleRepo) Save(ctx context.Context, u *ArticleInput, authorID uuid.U
 	if err != nil {
 		return nil, err
 	}
+	if err := a.saveRevision(ctx, newArticle.ID, newArticle.Title, newArticle.Body, authorID, tx); err != nil {
+		return nil, err
+	}
 	return &newArticle, nil
 }
 
-// Update implements ArticleRepository.
-func (a *ArticleRepo) Update(ctx context.Context, u *ArticleInput, id uuid.UUID, authorID uuid.UUID, tx *sqlx.Tx) (*uuid.UUID, error) {
-	article := u.ToArticleUpdate(id, authorID)
-	_, err := tx.NamedExecContext(ctx, UpdateArticleQuery, article)
+// Update only succeeds while the row is still at version; it returns
+// sql.ErrNoRows otherwise. The new revision is recorded as edited by
+// editorID, who is not necessarily the author.
+func (a *ArticleRepo) Update(ctx context.Context, u *ArticleInput, id uuid.UUID, editorID uuid.UUID, version int, tx *sqlx.Tx) (*uuid.UUID, error) {
+	article := u.ToArticleUpdate(id, editorID, version)
+	result, err := tx.NamedExecContext(ctx, UpdateArticleQuery, article)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := result.RowsAffected()
 	if err != nil {
 		return nil, err
 	}
+	if updated == 0 {
+		return nil, sql.ErrNoRows
+	}
+	if err := a.saveRevision(ctx, article.ID, article.Title, article.Body, editorID, tx); err != nil {
+		return nil, err
+	}
 	return &article.ID, nil
 }
 
@@ -56,6 +76,9 @@ func (a *ArticleRepo) CreateManyArticle(ctx context.Context, u []*ArticleInput,
 		if err != nil {
 			return nil, err
 		}
+		if err := a.saveRevision(ctx, article.ID, article.Title, article.Body, authorID, tx); err != nil {
+			return nil, err
+		}
 	}
 
 	return articles, nil
@@ -77,3 +100,186 @@ func (a *ArticleRepo) FindAllArticleWithAuthorByAuthorID(ctx context.Context, id
 	}
 	return articles, nil
 }
+
+// FindByIDForUpdate locks the row for the rest of tx. Soft-deleted rows are
+// returned as well so callers can tell them apart from missing ones.
+func (a *ArticleRepo) FindByIDForUpdate(ctx context.Context, id uuid.UUID, tx *sqlx.Tx) (*Article, error) {
+	var article Article
+	if err := tx.GetContext(ctx, &article, FindArticleByIDForUpdateQuery, id); err != nil {
+		return nil, err
+	}
+	return &article, nil
+}
+
+func (a *ArticleRepo) Delete(ctx context.Context, id uuid.UUID, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, SoftDeleteArticleQuery, id, time.Now())
+	return err
+}
+
+func (a *ArticleRepo) Restore(ctx context.Context, id uuid.UUID, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, RestoreArticleQuery, id, time.Now())
+	return err
+}
+
+func (a *ArticleRepo) UpdateStatus(ctx context.Context, id uuid.UUID, status Status, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, UpdateArticleStatusQuery, id, status, time.Now())
+	return err
+}
+
+func (a *ArticleRepo) Schedule(ctx context.Context, id uuid.UUID, publishAt time.Time, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, ScheduleArticleQuery, id, publishAt, time.Now())
+	return err
+}
+
+// FindDueScheduledForUpdate locks up to limit scheduled articles whose
+// publish_at has passed. Rows already locked by another replica are skipped.
+func (a *ArticleRepo) FindDueScheduledForUpdate(ctx context.Context, now time.Time, limit int, tx *sqlx.Tx) ([]*Article, error) {
+	var articles []*Article
+	if err := tx.SelectContext(ctx, &articles, FindDueScheduledArticleForUpdateQuery, now, limit); err != nil {
+		return nil, err
+	}
+	return articles, nil
+}
+
+// PurgeDeleted hard-deletes rows soft-deleted before the given time.
+func (a *ArticleRepo) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	result, err := a.db.ExecContext(ctx, PurgeDeletedArticleQuery, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (a *ArticleRepo) FindRevisions(ctx context.Context, articleID uuid.UUID) ([]*Revision, error) {
+	var revisions []*Revision
+	if err := a.db.SelectContext(ctx, &revisions, FindArticleRevisionsQuery, articleID); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+func (a *ArticleRepo) FindRevision(ctx context.Context, articleID uuid.UUID, revision int) (*Revision, error) {
+	var rev Revision
+	if err := a.db.GetContext(ctx, &rev, FindArticleRevisionQuery, articleID, revision); err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+// saveRevision snapshots the article content inside tx, so a revision exists
+// for every committed write of title or body.
+func (a *ArticleRepo) saveRevision(ctx context.Context, articleID uuid.UUID, title string, body string, editedBy uuid.UUID, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, CreateArticleRevisionQuery, uuid.New(), articleID, title, body, editedBy)
+	return err
+}
+
+// EnqueueOutbox records an index change inside tx; the OutboxRelay applies it
+// to Elasticsearch after commit.
+func (a *ArticleRepo) EnqueueOutbox(ctx context.Context, eventType OutboxEventType, aggregateID uuid.UUID, payload interface{}, tx *sqlx.Tx) error {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, CreateOutboxEventQuery, uuid.New(), aggregateID, eventType, string(raw))
+	return err
+}
+
+// ClaimPendingOutbox claims up to limit due events, oldest first, for lease:
+// until then no other relay picks them up. Due times are compared with the
+// database clock, which also sets them.
+func (a *ArticleRepo) ClaimPendingOutbox(ctx context.Context, limit int, lease time.Duration) ([]*OutboxEvent, error) {
+	var events []*OutboxEvent
+	if err := a.db.SelectContext(ctx, &events, ClaimPendingOutboxQuery, limit, lease.Seconds()); err != nil {
+		return nil, err
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Seq < events[j].Seq })
+	return events, nil
+}
+
+func (a *ArticleRepo) DeleteOutboxEvent(ctx context.Context, id uuid.UUID) error {
+	_, err := a.db.ExecContext(ctx, DeleteOutboxEventQuery, id)
+	return err
+}
+
+// MarkOutboxEventFailed stores the failed attempt of event; a pending event
+// becomes due again after retryIn.
+func (a *ArticleRepo) MarkOutboxEventFailed(ctx context.Context, event *OutboxEvent, retryIn time.Duration) error {
+	_, err := a.db.ExecContext(ctx, UpdateOutboxEventFailureQuery, event.ID, event.Status, event.Attempts, retryIn.Seconds(), event.LastError)
+	return err
+}
+
+func (a *ArticleRepo) CountOutboxByStatus(ctx context.Context) (map[string]int64, error) {
+	var rows []struct {
+		Status string `db:"status"`
+		Count  int64  `db:"count"`
+	}
+	if err := a.db.SelectContext(ctx, &rows, CountOutboxByStatusQuery); err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+// FindForReindex returns up to limit live articles with their author, ordered
+// by id and starting after afterID.
+func (a *ArticleRepo) FindForReindex(ctx context.Context, afterID uuid.UUID, limit int) ([]*Article, error) {
+	var articles []*Article
+	if err := a.db.SelectContext(ctx, &articles, FindArticlesForReindexQuery, afterID, limit); err != nil {
+		return nil, err
+	}
+	return articles, nil
+}
+
+// FindChangedSinceForReindex is FindForReindex restricted to rows updated
+// since the given time, deleted ones included.
+func (a *ArticleRepo) FindChangedSinceForReindex(ctx context.Context, since time.Time, afterID uuid.UUID, limit int) ([]*Article, error) {
+	var articles []*Article
+	if err := a.db.SelectContext(ctx, &articles, FindArticlesChangedSinceForReindexQuery, afterID, since, limit); err != nil {
+		return nil, err
+	}
+	return articles, nil
+}
+
+func (a *ArticleRepo) CountForReindex(ctx context.Context) (int64, error) {
+	var count int64
+	if err := a.db.GetContext(ctx, &count, CountArticlesForReindexQuery); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (a *ArticleRepo) FindUnfinishedReindexCheckpoint(ctx context.Context) (*ReindexCheckpoint, error) {
+	var checkpoint ReindexCheckpoint
+	if err := a.db.GetContext(ctx, &checkpoint, FindUnfinishedReindexCheckpointQuery); err != nil {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
+
+func (a *ArticleRepo) SaveReindexCheckpoint(ctx context.Context, checkpoint *ReindexCheckpoint) error {
+	_, err := a.db.NamedExecContext(ctx, SaveReindexCheckpointQuery, checkpoint)
+	return err
+}
+
+// FindByAuthorForIndex loads every live article of the author, joined with
+// the author fields that are denormalized into article documents.
+func (a *ArticleRepo) FindByAuthorForIndex(ctx context.Context, authorID uuid.UUID) ([]*Article, error) {
+	var articles []*Article
+	if err := a.db.SelectContext(ctx, &articles, FindArticlesByAuthorForIndexQuery, authorID); err != nil {
+		return nil, err
+	}
+	return articles, nil
+}
+
+// FindAuthorForIndex loads the author fields that are denormalized into
+// article documents.
+func (a *ArticleRepo) FindAuthorForIndex(ctx context.Context, authorID uuid.UUID) (*authors.Author, error) {
+	var author authors.Author
+	if err := a.db.GetContext(ctx, &author, FindAuthorForIndexQuery, authorID); err != nil {
+		return nil, err
+	}
+	return &author, nil
+}
@@ -8,19 +8,35 @@ import (
 )
 
 type Article struct {
-	ID        uuid.UUID `db:"id" json:"id"`
-	Title     string    `db:"title" json:"title"`
-	Body      string    `db:"body" json:"body"`
-	AuthorID  uuid.UUID `db:"author_id" json:"author_id"`
-	CreatedAt time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+	ID        uuid.UUID  `db:"id" json:"id"`
+	Title     string     `db:"title" json:"title"`
+	Body      string     `db:"body" json:"body"`
+	AuthorID  uuid.UUID  `db:"author_id" json:"author_id"`
+	Status    Status     `db:"status" json:"status"`
+	PublishAt *time.Time `db:"publish_at" json:"publish_at,omitempty"`
+	Version   int        `db:"version" json:"version"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time  `db:"updated_at" json:"updated_at"`
+	DeletedAt *time.Time `db:"deleted_at" json:"deleted_at,omitempty"`
+
+	// Score is the relevance of the article to a keyword search. It is only
+	// set on search results.
+	Score *float64 `db:"-" json:"score,omitempty"`
+	// Highlights holds the matching fragments per field and Snippet a short
+	// extract of the body. Both are only set on search results.
+	Highlights map[string][]string `db:"-" json:"highlights,omitempty"`
+	Snippet    string              `db:"-" json:"snippet,omitempty"`
 
 	Author *authors.Author `db:"author" json:"author"`
 }
 
 type ArticleInput struct {
-	Title    string    `json:"title"`
-	Body     string    `json:"body"`
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+type ScheduleArticleInput struct {
+	PublishAt time.Time `json:"publish_at"`
 }
 
 type ArticleInputUpdate struct {
@@ -28,12 +44,14 @@ type ArticleInputUpdate struct {
 	Title     string    `db:"title" json:"title"`
 	Body      string    `db:"body" json:"body"`
 	AuthorID  uuid.UUID `db:"author_id" json:"author_id"`
+	Version   int       `db:"version" json:"version"`
 	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
 }
 
 type ArticleWithAuthor struct {
 	authors.Author
-	Article []*Article `json:"article"`
+	Article    []*Article `json:"article"`
+	NextCursor string     `json:"next_cursor,omitempty"`
 }
 
 func (a *Article) TableName() string {
@@ -46,6 +64,8 @@ func CreateNewArticle(input ArticleInput, authorID uuid.UUID) Article {
 		Title:     input.Title,
 		Body:      input.Body,
 		AuthorID:  authorID,
+		Status:    StatusDraft,
+		Version:   1,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
@@ -59,12 +79,13 @@ func CreateManyArticle(input []*ArticleInput, authorID uuid.UUID) []Article {
 	return articles
 }
 
-func (a *ArticleInput) ToArticleUpdate(id uuid.UUID, authorID uuid.UUID) ArticleInputUpdate {
+func (a *ArticleInput) ToArticleUpdate(id uuid.UUID, authorID uuid.UUID, version int) ArticleInputUpdate {
 	return ArticleInputUpdate{
 		ID:        id,
 		Title:     a.Title,
 		Body:      a.Body,
 		AuthorID:  authorID,
+		Version:   version,
 		UpdatedAt: time.Now(),
 	}
 }
@@ -0,0 +1,8 @@
+
+-- +migrate Up
+ALTER TABLE articles ADD COLUMN deleted_at TIMESTAMP NULL;
+CREATE INDEX idx_articles_deleted_at ON articles (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- +migrate Down
+DROP INDEX idx_articles_deleted_at;
+ALTER TABLE articles DROP COLUMN deleted_at;
@@ -0,0 +1,14 @@
+
+-- +migrate Up
+-- Existing articles were already public, so they start out published; new
+-- rows default to draft.
+ALTER TABLE articles ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'published';
+ALTER TABLE articles ALTER COLUMN status SET DEFAULT 'draft';
+ALTER TABLE articles ADD CONSTRAINT chk_articles_status
+	CHECK (status IN ('draft', 'in_review', 'published', 'archived'));
+CREATE INDEX idx_articles_status ON articles (status);
+
+-- +migrate Down
+DROP INDEX idx_articles_status;
+ALTER TABLE articles DROP CONSTRAINT chk_articles_status;
+ALTER TABLE articles DROP COLUMN status;
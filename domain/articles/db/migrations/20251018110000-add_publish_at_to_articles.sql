@@ -0,0 +1,15 @@
+
+-- +migrate Up
+ALTER TABLE articles ADD COLUMN publish_at TIMESTAMP NULL;
+ALTER TABLE articles DROP CONSTRAINT chk_articles_status;
+ALTER TABLE articles ADD CONSTRAINT chk_articles_status
+	CHECK (status IN ('draft', 'in_review', 'scheduled', 'published', 'archived'));
+CREATE INDEX idx_articles_scheduled_publish_at ON articles (publish_at) WHERE status = 'scheduled';
+
+-- +migrate Down
+DROP INDEX idx_articles_scheduled_publish_at;
+UPDATE articles SET status = 'draft' WHERE status = 'scheduled';
+ALTER TABLE articles DROP CONSTRAINT chk_articles_status;
+ALTER TABLE articles ADD CONSTRAINT chk_articles_status
+	CHECK (status IN ('draft', 'in_review', 'published', 'archived'));
+ALTER TABLE articles DROP COLUMN publish_at;
@@ -0,0 +1,20 @@
+
+-- +migrate Up
+CREATE TABLE article_revisions (
+	id UUID PRIMARY KEY,
+	article_id UUID NOT NULL REFERENCES articles (id) ON DELETE CASCADE,
+	revision INT NOT NULL,
+	title VARCHAR(255) NOT NULL,
+	body TEXT NOT NULL,
+	edited_by UUID NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (article_id, revision)
+);
+
+-- Existing articles start their history with their current content.
+INSERT INTO article_revisions (id, article_id, revision, title, body, edited_by, created_at)
+SELECT gen_random_uuid(), id, 1, title, body, author_id, COALESCE(updated_at, created_at)
+FROM articles;
+
+-- +migrate Down
+DROP TABLE article_revisions;
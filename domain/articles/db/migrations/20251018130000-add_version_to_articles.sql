@@ -0,0 +1,6 @@
+
+-- +migrate Up
+ALTER TABLE articles ADD COLUMN version INT NOT NULL DEFAULT 1;
+
+-- +migrate Down
+ALTER TABLE articles DROP COLUMN version;
@@ -0,0 +1,21 @@
+
+-- +migrate Up
+CREATE TABLE outbox (
+	id UUID PRIMARY KEY,
+	seq BIGSERIAL NOT NULL,
+	aggregate_id UUID NOT NULL,
+	event_type VARCHAR(50) NOT NULL,
+	payload JSONB NOT NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'pending',
+	attempts INT NOT NULL DEFAULT 0,
+	next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	last_error TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT chk_outbox_status CHECK (status IN ('pending', 'dead'))
+);
+
+CREATE INDEX idx_outbox_pending ON outbox (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX idx_outbox_aggregate ON outbox (aggregate_id, seq) WHERE status = 'pending';
+
+-- +migrate Down
+DROP TABLE outbox;
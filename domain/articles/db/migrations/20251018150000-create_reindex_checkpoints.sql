@@ -0,0 +1,12 @@
+
+-- +migrate Up
+CREATE TABLE reindex_checkpoints (
+	index_name VARCHAR(255) PRIMARY KEY,
+	last_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000',
+	indexed BIGINT NOT NULL DEFAULT 0,
+	started_at TIMESTAMP NOT NULL,
+	completed_at TIMESTAMP
+);
+
+-- +migrate Down
+DROP TABLE reindex_checkpoints;
@@ -3,7 +3,14 @@ package articles
 import "github.com/afif-musyayyidin/hertz-boilerplate/domain/infra"
 
 var (
-	ErrNotFound     = infra.New("NOT_FOUND", "Not found")
-	ErrInvalidInput = infra.New("INVALID_INPUT", "Invalid input")
-	ErrInternal     = infra.New("INTERNAL_SERVER_ERROR", "Internal server error")
+	ErrNotFound     = infra.New(infra.CodeNotFound, "Not found")
+	ErrInvalidInput = infra.New(infra.CodeInvalidInput, "Invalid input")
+	ErrForbidden    = infra.New(infra.CodeForbidden, "Forbidden")
+	ErrInternal     = infra.New(infra.CodeInternalServer, "Internal server error")
+
+	ErrInvalidStatusTransition = infra.New(infra.CodeConflict, "Invalid status transition")
+	ErrVersionRequired         = infra.New(infra.CodePreconditionRequired, "Article version required")
+	ErrVersionMismatch         = infra.New(infra.CodePreconditionFailed, "Article has been modified")
+
+	ErrEmailNotVerified = infra.New(infra.CodeForbidden, "Verify your email before publishing")
 )
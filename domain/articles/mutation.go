@@ -2,21 +2,40 @@ package articles
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 
 	"github.com/afif-musyayyidin/hertz-boilerplate/domain/authors"
+	"github.com/afif-musyayyidin/hertz-boilerplate/domain/infra"
+	"github.com/afif-musyayyidin/hertz-boilerplate/domain/infra/logger"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 )
 
 type ArticleMutation interface {
 	CreateArticle(ctx context.Context, u *ArticleInput, authorID uuid.UUID) (*uuid.UUID, error)
-	UpdateArticle(ctx context.Context, u *ArticleInput, id uuid.UUID, authorID uuid.UUID) (*uuid.UUID, error)
-	GetArticleByKeyWord(ctx context.Context, keyword string) ([]*Article, error)
+	UpdateArticle(ctx context.Context, u *ArticleInput, id uuid.UUID, authorID uuid.UUID, version int) (*Article, error)
+	GetArticleByKeyWord(ctx context.Context, req SearchRequest, viewerID uuid.UUID, page PageRequest) (*ArticlePage, error)
+	SuggestArticle(ctx context.Context, prefix string, viewerID uuid.UUID, size int) ([]*Suggestion, error)
+	GetRelatedArticles(ctx context.Context, id uuid.UUID, viewerID uuid.UUID, size int) ([]*Article, error)
 	CreateManyArticle(ctx context.Context, u []*ArticleInput, authorID uuid.UUID) ([]*uuid.UUID, error)
-	GetArticleWithAuthorByID(ctx context.Context, id uuid.UUID) (*ArticleWithAuthor, error)
-	GetArticleByAuthorName(ctx context.Context, name string) ([]*ArticleWithAuthor, error)
-	GetArticleByID(ctx context.Context, id uuid.UUID) (*Article, error)
-	GetAllArticle(ctx context.Context) ([]*Article, error)
+	GetArticleWithAuthorByID(ctx context.Context, id uuid.UUID, viewerID uuid.UUID, page PageRequest) (*ArticleWithAuthor, error)
+	GetArticleByAuthorName(ctx context.Context, name string, viewerID uuid.UUID, page PageRequest) (*ArticleWithAuthorPage, error)
+	GetArticleByID(ctx context.Context, id uuid.UUID, viewerID uuid.UUID) (*Article, error)
+	GetAllArticle(ctx context.Context, viewerID uuid.UUID, page PageRequest) (*ArticlePage, error)
+	DeleteArticle(ctx context.Context, id uuid.UUID, authorID uuid.UUID) error
+	RestoreArticle(ctx context.Context, id uuid.UUID, authorID uuid.UUID) (*uuid.UUID, error)
+	PublishArticle(ctx context.Context, id uuid.UUID, authorID uuid.UUID) (*uuid.UUID, error)
+	UnpublishArticle(ctx context.Context, id uuid.UUID, authorID uuid.UUID) (*uuid.UUID, error)
+	ArchiveArticle(ctx context.Context, id uuid.UUID, authorID uuid.UUID) (*uuid.UUID, error)
+	ScheduleArticle(ctx context.Context, id uuid.UUID, authorID uuid.UUID, publishAt time.Time) (*uuid.UUID, error)
+	GetArticleRevisions(ctx context.Context, id uuid.UUID, authorID uuid.UUID) ([]*Revision, error)
+	GetArticleRevision(ctx context.Context, id uuid.UUID, revision int, authorID uuid.UUID) (*RevisionDiff, error)
+	RestoreArticleRevision(ctx context.Context, id uuid.UUID, revision int, authorID uuid.UUID) (*uuid.UUID, error)
 }
 
 type articleMutation struct {
@@ -46,7 +65,7 @@ func (m *articleMutation) CreateArticle(ctx context.Context, u *ArticleInput, au
 		return nil, err
 	}
 
-	if err := m.index.Index(ctx, newArticle); err != nil {
+	if err := m.repo.EnqueueOutbox(ctx, EventArticleUpsert, newArticle.ID, newArticle, tx); err != nil {
 		_ = tx.Rollback()
 		return nil, err
 	}
@@ -57,24 +76,38 @@ func (m *articleMutation) CreateArticle(ctx context.Context, u *ArticleInput, au
 	return &newArticle.ID, nil
 }
 
-func (m *articleMutation) GetArticleByID(ctx context.Context, id uuid.UUID) (*Article, error) {
+// GetArticleByID returns the article with its author. An article that is
+// not published is only visible to those who may edit it and reported as not
+// found to anyone else.
+func (m *articleMutation) GetArticleByID(ctx context.Context, id uuid.UUID, viewerID uuid.UUID) (*Article, error) {
 	article, err := m.repo.FindByID(ctx, id)
-	if err != nil {
+	if errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrNotFound.WithDetails(map[string]interface{}{
 			"id": id,
 		})
 	}
+	if err != nil {
+		return nil, err
+	}
+	if err := m.checkViewer(ctx, article, viewerID); err != nil {
+		return nil, err
+	}
 	getAuthor, err := m.author.GetAuthorByID(ctx, article.AuthorID)
 	if err != nil {
-		return nil, ErrNotFound.WithDetails(map[string]interface{}{
-			"id": article.AuthorID,
-		})
+		return nil, err
 	}
 	article.Author = getAuthor
 	return article, nil
 }
 
-func (m *articleMutation) UpdateArticle(ctx context.Context, u *ArticleInput, id uuid.UUID, authorID uuid.UUID) (*uuid.UUID, error) {
+// UpdateArticle saves the new content only if the article is still at
+// version, the version the caller last read.
+func (m *articleMutation) UpdateArticle(ctx context.Context, u *ArticleInput, id uuid.UUID, authorID uuid.UUID, version int) (*Article, error) {
+	if version <= 0 {
+		return nil, ErrVersionRequired.WithDetails(map[string]interface{}{
+			"id": id,
+		})
+	}
 	if u == nil {
 		return nil, ErrInvalidInput.WithDetails(map[string]interface{}{
 			"id": id,
@@ -89,25 +122,54 @@ func (m *articleMutation) UpdateArticle(ctx context.Context, u *ArticleInput, id
 	if err != nil {
 		return nil, err
 	}
-	idResult, err := m.repo.Update(ctx, u, id, authorID, tx)
+	article, err := m.findOwnedForUpdate(ctx, id, authorID, tx)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if article.DeletedAt != nil {
+		_ = tx.Rollback()
+		return nil, ErrNotFound.WithDetails(map[string]interface{}{
+			"id": id,
+		})
+	}
+	if article.Version != version {
+		_ = tx.Rollback()
+		return nil, ErrVersionMismatch.WithDetails(map[string]interface{}{
+			"id":      id,
+			"version": article.Version,
+		})
+	}
+	if article.Title == u.Title && article.Body == u.Body {
+		_ = tx.Rollback()
+		return article, nil
+	}
+	_, err = m.repo.Update(ctx, u, id, authorID, version, tx)
+	if errors.Is(err, sql.ErrNoRows) {
+		_ = tx.Rollback()
+		return nil, ErrNotFound.WithDetails(map[string]interface{}{
+			"id": id,
+		})
+	}
 	if err != nil {
 		_ = tx.Rollback()
 		return nil, err
 	}
-	if err := m.index.UpdateField(ctx, id.String(), map[string]interface{}{
-		"title": u.Title,
-		"body":  u.Body,
-	}); err != nil {
+	article.Title = u.Title
+	article.Body = u.Body
+	article.Version++
+	article.UpdatedAt = time.Now()
+	if err := m.repo.EnqueueOutbox(ctx, EventArticleUpsert, article.ID, article, tx); err != nil {
 		_ = tx.Rollback()
 		return nil, err
 	}
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
-	return idResult, nil
+	return article, nil
 }
 
-func (m *articleMutation) GetArticleByAuthorName(ctx context.Context, name string) ([]*ArticleWithAuthor, error) {
+func (m *articleMutation) GetArticleByAuthorName(ctx context.Context, name string, viewerID uuid.UUID, page PageRequest) (*ArticleWithAuthorPage, error) {
 	var (
 		authorIDList               []uuid.UUID
 		authorArticleWithAuthorMap = make(map[uuid.UUID]*ArticleWithAuthor)
@@ -127,53 +189,131 @@ func (m *articleMutation) GetArticleByAuthorName(ctx context.Context, name strin
 			},
 		}
 	}
-	articleList, err := m.index.GetArticleByAuthorIDList(ctx, authorIDList)
+	if len(authorIDList) == 0 {
+		return &ArticleWithAuthorPage{Authors: []*ArticleWithAuthor{}}, nil
+	}
+	articlePage, err := m.index.GetArticleByAuthorIDList(ctx, authorIDList, viewerID, page)
 	if err != nil {
-		return nil, ErrNotFound.WithDetails(map[string]interface{}{
-			"authorIDList": authorIDList,
-		})
+		return nil, err
 	}
-	for _, article := range articleList {
+	for _, article := range articlePage.Articles {
 		if _, ok := authorArticleWithAuthorMap[article.AuthorID]; !ok {
 			continue
 		}
 		authorArticleWithAuthorMap[article.AuthorID].Article = append(authorArticleWithAuthorMap[article.AuthorID].Article, article)
 	}
-	var articleWithAuthorList []*ArticleWithAuthor
-	for _, articleWithAuthor := range authorArticleWithAuthorMap {
-		articleWithAuthorList = append(articleWithAuthorList, articleWithAuthor)
+	// follow the author list rather than the map, so every request for the
+	// page lists the authors in the same order; authors without an article
+	// on this page are left out
+	articleWithAuthorList := make([]*ArticleWithAuthor, 0, len(getIDNameListAuthor))
+	for _, author := range getIDNameListAuthor {
+		if articleWithAuthor := authorArticleWithAuthorMap[author.ID]; len(articleWithAuthor.Article) > 0 {
+			articleWithAuthorList = append(articleWithAuthorList, articleWithAuthor)
+		}
 	}
-	return articleWithAuthorList, nil
+	return &ArticleWithAuthorPage{
+		Authors:    articleWithAuthorList,
+		NextCursor: articlePage.NextCursor,
+	}, nil
+}
+
+// GetArticleByKeyWord searches articles. Hits carry the author copied into
+// the article document, so no database round trip is needed.
+func (m *articleMutation) GetArticleByKeyWord(ctx context.Context, req SearchRequest, viewerID uuid.UUID, page PageRequest) (*ArticlePage, error) {
+	return m.index.Search(ctx, req, viewerID, page)
 }
 
-func (m *articleMutation) GetArticleByKeyWord(ctx context.Context, keyword string) ([]*Article, error) {
+// SuggestArticle looks up article titles in ES and author names in Postgres
+// at the same time, within SuggestTimeout. A source that fails or runs out
+// of time is left out; only when both fail an error is returned.
+func (m *articleMutation) SuggestArticle(ctx context.Context, prefix string, viewerID uuid.UUID, size int) ([]*Suggestion, error) {
+	prefix = strings.TrimSpace(prefix)
+	if utf8.RuneCountInString(prefix) < MinSuggestLength {
+		return []*Suggestion{}, nil
+	}
+	size = suggestSize(size)
+
+	ctx, cancel := context.WithTimeout(ctx, SuggestTimeout)
+	defer cancel()
+
 	var (
-		authorList   = make(map[uuid.UUID]authors.Author)
-		idAuthorList []uuid.UUID
+		wg                      sync.WaitGroup
+		articleList, authorList []*Suggestion
+		articleErr, authorErr   error
 	)
-	articleList, err := m.index.Search(ctx, keyword)
-	if err != nil {
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		articleList, articleErr = m.index.Suggest(ctx, prefix, viewerID, size)
+	}()
+	go func() {
+		defer wg.Done()
+		var authorSuggestions []*authors.AuthorSuggestion
+		authorSuggestions, authorErr = m.author.SuggestAuthorByName(ctx, prefix, size)
+		for _, author := range authorSuggestions {
+			authorList = append(authorList, &Suggestion{
+				Type:  SuggestionAuthor,
+				ID:    author.ID,
+				Text:  author.Name,
+				Score: author.Score,
+			})
+		}
+	}()
+	wg.Wait()
+
+	if articleErr != nil && authorErr != nil {
+		return nil, articleErr
+	}
+	if articleErr != nil {
+		logger.Debug("article suggestions left out", articleErr)
+	}
+	if authorErr != nil {
+		logger.Debug("author suggestions left out", authorErr)
+	}
+	return mergeSuggestions(size, articleList, authorList), nil
+}
+
+// GetRelatedArticles returns up to size published articles similar to the
+// article id, with their authors. An article viewerID may not see is
+// reported as not found.
+func (m *articleMutation) GetRelatedArticles(ctx context.Context, id uuid.UUID, viewerID uuid.UUID, size int) ([]*Article, error) {
+	article, err := m.repo.FindByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrNotFound.WithDetails(map[string]interface{}{
-			"keyword": keyword,
+			"id": id,
 		})
 	}
-	for _, article := range articleList {
-		idAuthorList = append(idAuthorList, article.AuthorID)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.checkViewer(ctx, article, viewerID); err != nil {
+		return nil, err
+	}
+
+	related, err := m.index.GetRelatedArticles(ctx, article, relatedSize(size))
+	if err != nil {
+		return nil, err
+	}
+	if len(related) == 0 {
+		return related, nil
+	}
+	var idAuthorList []uuid.UUID
+	for _, a := range related {
+		idAuthorList = append(idAuthorList, a.AuthorID)
 	}
 	authorListResult, err := m.author.GetAuthorByIDList(ctx, idAuthorList)
 	if err != nil {
-		return nil, ErrNotFound.WithDetails(map[string]interface{}{
-			"id_author_list": idAuthorList,
-		})
+		return nil, err
 	}
+	authorList := make(map[uuid.UUID]authors.Author, len(authorListResult))
 	for _, author := range authorListResult {
 		authorList[author.ID] = author
 	}
-	for _, article := range articleList {
-		author := authorList[article.AuthorID]
-		article.Author = &author
+	for _, a := range related {
+		author := authorList[a.AuthorID]
+		a.Author = &author
 	}
-	return articleList, nil
+	return related, nil
 }
 
 func (m *articleMutation) CreateManyArticle(ctx context.Context, u []*ArticleInput, authorID uuid.UUID) ([]*uuid.UUID, error) {
@@ -185,16 +325,12 @@ func (m *articleMutation) CreateManyArticle(ctx context.Context, u []*ArticleInp
 	articleList, err := m.repo.CreateManyArticle(ctx, u, authorID, tx)
 	if err != nil {
 		_ = tx.Rollback()
-		return nil, ErrNotFound.WithDetails(map[string]interface{}{
-			"article_list": articleList,
-		})
+		return nil, err
 	}
 	for _, article := range articleList {
-		if err := m.index.Index(ctx, &article); err != nil {
+		if err := m.repo.EnqueueOutbox(ctx, EventArticleUpsert, article.ID, article, tx); err != nil {
 			_ = tx.Rollback()
-			return nil, ErrNotFound.WithDetails(map[string]interface{}{
-				"article": article,
-			})
+			return nil, err
 		}
 	}
 	for _, article := range articleList {
@@ -206,7 +342,7 @@ func (m *articleMutation) CreateManyArticle(ctx context.Context, u []*ArticleInp
 	return articleListID, nil
 }
 
-func (m *articleMutation) GetArticleWithAuthorByID(ctx context.Context, id uuid.UUID) (*ArticleWithAuthor, error) {
+func (m *articleMutation) GetArticleWithAuthorByID(ctx context.Context, id uuid.UUID, viewerID uuid.UUID, page PageRequest) (*ArticleWithAuthor, error) {
 
 	getAuthor, err := m.author.GetAuthorByID(ctx, id)
 	if err != nil {
@@ -215,11 +351,9 @@ func (m *articleMutation) GetArticleWithAuthorByID(ctx context.Context, id uuid.
 		})
 	}
 
-	getArticle, err := m.index.GetArticleByAuthorID(ctx, getAuthor.ID)
+	articlePage, err := m.index.GetArticleByAuthorID(ctx, getAuthor.ID, viewerID, page)
 	if err != nil {
-		return nil, ErrNotFound.WithDetails(map[string]interface{}{
-			"id_author": getAuthor.ID,
-		})
+		return nil, err
 	}
 
 	// getArticle, err := m.repo.FindAllArticleWithAuthorByAuthorID(ctx, getAuthor.ID)
@@ -228,19 +362,18 @@ func (m *articleMutation) GetArticleWithAuthorByID(ctx context.Context, id uuid.
 	// }
 
 	return &ArticleWithAuthor{
-		Author:  *getAuthor,
-		Article: getArticle,
+		Author:     *getAuthor,
+		Article:    articlePage.Articles,
+		NextCursor: articlePage.NextCursor,
 	}, nil
 }
 
-func (m *articleMutation) GetAllArticle(ctx context.Context) ([]*Article, error) {
-	articleList, err := m.index.GetAllArticle(ctx)
+func (m *articleMutation) GetAllArticle(ctx context.Context, viewerID uuid.UUID, page PageRequest) (*ArticlePage, error) {
+	articlePage, err := m.index.GetAllArticle(ctx, viewerID, page)
 	if err != nil {
-		return nil, ErrNotFound.WithDetails(map[string]interface{}{
-			"error": err,
-		})
+		return nil, err
 	}
-	for _, article := range articleList {
+	for _, article := range articlePage.Articles {
 		getAuthor, err := m.author.GetAuthorByID(ctx, article.AuthorID)
 		if err != nil {
 			return nil, ErrNotFound.WithDetails(map[string]interface{}{
@@ -249,5 +382,388 @@ func (m *articleMutation) GetAllArticle(ctx context.Context) ([]*Article, error)
 		}
 		article.Author = getAuthor
 	}
-	return articleList, nil
+	return articlePage, nil
+}
+
+func (m *articleMutation) DeleteArticle(ctx context.Context, id uuid.UUID, authorID uuid.UUID) error {
+	if id == uuid.Nil {
+		return ErrInvalidInput.WithDetails(map[string]interface{}{
+			"id": id,
+		})
+	}
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	article, err := m.findOwnedForUpdate(ctx, id, authorID, tx)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if article.DeletedAt != nil {
+		_ = tx.Rollback()
+		return ErrNotFound.WithDetails(map[string]interface{}{
+			"id": id,
+		})
+	}
+	if err := m.repo.Delete(ctx, id, tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := m.repo.EnqueueOutbox(ctx, EventArticleDelete, id, map[string]interface{}{
+		"id":      id,
+		"version": article.Version + 1,
+	}, tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m *articleMutation) RestoreArticle(ctx context.Context, id uuid.UUID, authorID uuid.UUID) (*uuid.UUID, error) {
+	if id == uuid.Nil {
+		return nil, ErrInvalidInput.WithDetails(map[string]interface{}{
+			"id": id,
+		})
+	}
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	article, err := m.findOwnedForUpdate(ctx, id, authorID, tx)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if article.DeletedAt == nil {
+		_ = tx.Rollback()
+		return nil, ErrNotFound.WithDetails(map[string]interface{}{
+			"id":      id,
+			"deleted": false,
+		})
+	}
+	if err := m.repo.Restore(ctx, id, tx); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	article.DeletedAt = nil
+	article.Version++
+	article.UpdatedAt = time.Now()
+	if err := m.repo.EnqueueOutbox(ctx, EventArticleUpsert, article.ID, article, tx); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &article.ID, nil
+}
+
+func (m *articleMutation) PublishArticle(ctx context.Context, id uuid.UUID, authorID uuid.UUID) (*uuid.UUID, error) {
+	return m.transitionArticle(ctx, id, authorID, StatusPublished)
+}
+
+func (m *articleMutation) UnpublishArticle(ctx context.Context, id uuid.UUID, authorID uuid.UUID) (*uuid.UUID, error) {
+	return m.transitionArticle(ctx, id, authorID, StatusDraft)
+}
+
+func (m *articleMutation) ArchiveArticle(ctx context.Context, id uuid.UUID, authorID uuid.UUID) (*uuid.UUID, error) {
+	return m.transitionArticle(ctx, id, authorID, StatusArchived)
+}
+
+// ScheduleArticle marks the article to be published by the PublishScheduler
+// once publishAt has passed.
+func (m *articleMutation) ScheduleArticle(ctx context.Context, id uuid.UUID, authorID uuid.UUID, publishAt time.Time) (*uuid.UUID, error) {
+	if id == uuid.Nil || !publishAt.After(time.Now()) {
+		return nil, ErrInvalidInput.WithDetails(map[string]interface{}{
+			"id":         id,
+			"publish_at": publishAt,
+		})
+	}
+	if err := m.checkVerified(ctx, authorID); err != nil {
+		return nil, err
+	}
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	article, err := m.findOwnedForUpdate(ctx, id, authorID, tx)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if article.DeletedAt != nil {
+		_ = tx.Rollback()
+		return nil, ErrNotFound.WithDetails(map[string]interface{}{
+			"id": id,
+		})
+	}
+	if article.Status != StatusScheduled && !article.Status.CanTransitionTo(StatusScheduled) {
+		_ = tx.Rollback()
+		return nil, ErrInvalidStatusTransition.WithDetails(map[string]interface{}{
+			"id":   id,
+			"from": article.Status,
+			"to":   StatusScheduled,
+		})
+	}
+	if err := m.repo.Schedule(ctx, id, publishAt, tx); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	article.Status = StatusScheduled
+	article.PublishAt = &publishAt
+	article.Version++
+	article.UpdatedAt = time.Now()
+	if err := m.repo.EnqueueOutbox(ctx, EventArticleUpsert, article.ID, article, tx); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &article.ID, nil
+}
+
+func (m *articleMutation) GetArticleRevisions(ctx context.Context, id uuid.UUID, authorID uuid.UUID) ([]*Revision, error) {
+	if _, err := m.findOwned(ctx, id, authorID); err != nil {
+		return nil, err
+	}
+	revisions, err := m.repo.FindRevisions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// GetArticleRevision returns the revision together with its line diff
+// against the current title and body.
+func (m *articleMutation) GetArticleRevision(ctx context.Context, id uuid.UUID, revision int, authorID uuid.UUID) (*RevisionDiff, error) {
+	article, err := m.findOwned(ctx, id, authorID)
+	if err != nil {
+		return nil, err
+	}
+	rev, err := m.findRevision(ctx, id, revision)
+	if err != nil {
+		return nil, err
+	}
+	return &RevisionDiff{
+		Revision:  rev,
+		TitleDiff: DiffLines(rev.Title, article.Title),
+		BodyDiff:  DiffLines(rev.Body, article.Body),
+	}, nil
+}
+
+// RestoreArticleRevision copies an old revision back into the article. The
+// restore is recorded as a new revision; history is never rewritten.
+func (m *articleMutation) RestoreArticleRevision(ctx context.Context, id uuid.UUID, revision int, authorID uuid.UUID) (*uuid.UUID, error) {
+	if id == uuid.Nil {
+		return nil, ErrInvalidInput.WithDetails(map[string]interface{}{
+			"id": id,
+		})
+	}
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	article, err := m.findOwnedForUpdate(ctx, id, authorID, tx)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if article.DeletedAt != nil {
+		_ = tx.Rollback()
+		return nil, ErrNotFound.WithDetails(map[string]interface{}{
+			"id": id,
+		})
+	}
+	rev, err := m.findRevision(ctx, id, revision)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if article.Title == rev.Title && article.Body == rev.Body {
+		_ = tx.Rollback()
+		return &article.ID, nil
+	}
+	if _, err := m.repo.Update(ctx, &ArticleInput{Title: rev.Title, Body: rev.Body}, id, authorID, article.Version, tx); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	article.Title = rev.Title
+	article.Body = rev.Body
+	article.Version++
+	article.UpdatedAt = time.Now()
+	if err := m.repo.EnqueueOutbox(ctx, EventArticleUpsert, article.ID, article, tx); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &article.ID, nil
+}
+
+func (m *articleMutation) findRevision(ctx context.Context, id uuid.UUID, revision int) (*Revision, error) {
+	rev, err := m.repo.FindRevision(ctx, id, revision)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound.WithDetails(map[string]interface{}{
+			"id":       id,
+			"revision": revision,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rev, nil
+}
+
+// transitionArticle moves an article owned by authorID to the next status,
+// rejecting moves that statusTransitions does not allow.
+func (m *articleMutation) transitionArticle(ctx context.Context, id uuid.UUID, authorID uuid.UUID, next Status) (*uuid.UUID, error) {
+	if id == uuid.Nil {
+		return nil, ErrInvalidInput.WithDetails(map[string]interface{}{
+			"id": id,
+		})
+	}
+	if next == StatusPublished {
+		if err := m.checkVerified(ctx, authorID); err != nil {
+			return nil, err
+		}
+	}
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	article, err := m.findOwnedForUpdate(ctx, id, authorID, tx)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if article.DeletedAt != nil {
+		_ = tx.Rollback()
+		return nil, ErrNotFound.WithDetails(map[string]interface{}{
+			"id": id,
+		})
+	}
+	if !article.Status.CanTransitionTo(next) {
+		_ = tx.Rollback()
+		return nil, ErrInvalidStatusTransition.WithDetails(map[string]interface{}{
+			"id":   id,
+			"from": article.Status,
+			"to":   next,
+		})
+	}
+	if err := m.repo.UpdateStatus(ctx, id, next, tx); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	article.Status = next
+	if next == StatusDraft {
+		article.PublishAt = nil
+	}
+	article.Version++
+	article.UpdatedAt = time.Now()
+	if err := m.repo.EnqueueOutbox(ctx, EventArticleUpsert, article.ID, article, tx); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &article.ID, nil
+}
+
+// findOwned loads a live article and checks that authorID may edit it.
+func (m *articleMutation) findOwned(ctx context.Context, id uuid.UUID, authorID uuid.UUID) (*Article, error) {
+	article, err := m.repo.FindByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound.WithDetails(map[string]interface{}{
+			"id": id,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := m.checkEditor(ctx, article, authorID); err != nil {
+		return nil, err
+	}
+	return article, nil
+}
+
+// findOwnedForUpdate locks the article inside tx and checks that authorID
+// may edit it.
+func (m *articleMutation) findOwnedForUpdate(ctx context.Context, id uuid.UUID, authorID uuid.UUID, tx *sqlx.Tx) (*Article, error) {
+	article, err := m.repo.FindByIDForUpdate(ctx, id, tx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound.WithDetails(map[string]interface{}{
+			"id": id,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := m.checkEditor(ctx, article, authorID); err != nil {
+		return nil, err
+	}
+	return article, nil
+}
+
+// checkVerified returns ErrEmailNotVerified unless authorID has verified
+// their email. Publishing and scheduling need it; drafts do not.
+func (m *articleMutation) checkVerified(ctx context.Context, authorID uuid.UUID) error {
+	author, err := m.author.GetAuthorByID(ctx, authorID)
+	var apiErr *infra.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode == infra.CodeNotFound {
+		return ErrForbidden
+	}
+	if err != nil {
+		return err
+	}
+	if !author.EmailVerified() {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
+
+// checkViewer reports an article that is not published as not found unless
+// viewerID may edit it, so drafts are readable by whoever can change them.
+func (m *articleMutation) checkViewer(ctx context.Context, article *Article, viewerID uuid.UUID) error {
+	if article.Status == StatusPublished {
+		return nil
+	}
+	err := m.checkEditor(ctx, article, viewerID)
+	var apiErr *infra.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode == infra.CodeForbidden {
+		return ErrNotFound.WithDetails(map[string]interface{}{
+			"id": article.ID,
+		})
+	}
+	return err
+}
+
+// checkEditor returns ErrForbidden unless authorID owns the article or has a
+// role that may edit any article. The role is read from the primary, so a
+// revoked role takes effect before the token expires.
+func (m *articleMutation) checkEditor(ctx context.Context, article *Article, authorID uuid.UUID) error {
+	if article.AuthorID == authorID {
+		return nil
+	}
+	forbidden := ErrForbidden.WithDetails(map[string]interface{}{
+		"id": article.ID,
+	})
+	if authorID == uuid.Nil {
+		return forbidden
+	}
+	role, err := m.author.GetAuthorRole(ctx, authorID)
+	var apiErr *infra.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode == infra.CodeNotFound {
+		return forbidden
+	}
+	if err != nil {
+		return err
+	}
+	if !role.CanEditAnyArticle() {
+		return forbidden
+	}
+	return nil
 }
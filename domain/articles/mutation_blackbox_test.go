@@ -1,15 +1,23 @@
 package articles_test
 
 import (
+	"bytes"
 	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/afif-musyayyidin/hertz-boilerplate/config"
 	"github.com/afif-musyayyidin/hertz-boilerplate/domain/articles"
 	"github.com/afif-musyayyidin/hertz-boilerplate/domain/authors"
+	"github.com/afif-musyayyidin/hertz-boilerplate/domain/infra"
+	"github.com/afif-musyayyidin/hertz-boilerplate/middleware"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"github.com/olivere/elastic/v7"
@@ -58,11 +66,15 @@ func TestMain(m *testing.M) {
 }
 
 func cleanDB() {
+	testDB.Exec("DELETE FROM outbox")
 	testDB.Exec("DELETE FROM articles")
 	testDB.Exec("DELETE FROM authors")
 
+	testDB.Exec("DELETE FROM reindex_checkpoints")
+
+	es.DeleteIndex("articles_v*").Do(ctx)
 	es.DeleteIndex("articles").Do(ctx)
-	es.CreateIndex("articles").Do(ctx)
+	articles.NewArticleIndexer(es).EnsureIndex(ctx)
 }
 
 func newMutation() articles.ArticleMutation {
@@ -71,10 +83,36 @@ func newMutation() articles.ArticleMutation {
 	repo := articles.NewArticleRepo(ctx, testDB)
 
 	authorRepo := authors.NewAuthorRepo(testDB, testDB)
-	authorMutation := authors.NewAuthorMutation(authorRepo, testDB)
+	limiter := authors.NewLoginLimiter(authors.NewMemoryLoginAttemptStore(authors.DefaultLoginWindow), authors.DefaultEmailPolicy, authors.DefaultIPPolicy)
+	mail := authors.NewAccountMail(infra.NewFileMailer("", "no-reply@localhost"), "http://localhost:3000/reset-password", "http://localhost:8080/author/verify")
+	verifier, _ := authors.NewEmailVerifier([]byte(strings.Repeat("k", 32)))
+	_, private, _ := ed25519.GenerateKey(rand.Reader)
+	keys, _ := middleware.NewKeySet("test", private, nil)
+	authorMutation := authors.NewAuthorMutation(authorRepo, testDB, limiter, mail, verifier, keys)
 
 	return articles.NewArticleMutation(repo, indexer, testDB, authorMutation)
 }
+
+// verifyEmails marks every author as verified, so they may publish.
+func verifyEmails(t *testing.T) {
+	_, err := testDB.Exec("UPDATE authors SET email_verified_at = NOW()")
+	require.NoError(t, err)
+}
+
+// relayOutbox pushes every pending outbox event into Elasticsearch and makes
+// it searchable.
+func relayOutbox(t *testing.T) {
+	relay := articles.NewOutboxRelay(articles.NewArticleRepo(ctx, testDB), articles.NewArticleIndexer(es), time.Second, 100, 3)
+	for {
+		processed, err := relay.RelayPending(ctx)
+		require.NoError(t, err)
+		if processed == 0 {
+			break
+		}
+	}
+	_, _ = es.Refresh("articles").Do(ctx)
+}
+
 func TestCreateArticle(t *testing.T) {
 	cleanDB()
 	mutation := newMutation()
@@ -97,8 +135,8 @@ func TestCreateArticle(t *testing.T) {
 	assert.NoError(t, err)
 
 	input := articles.ArticleInput{
-		Title:    "Test Article",
-		Body:     "Test Body",
+		Title: "Test Article",
+		Body:  "Test Body",
 	}
 	id, err := mutation.CreateArticle(ctx, &input, authorID)
 	assert.NoError(t, err)
@@ -111,6 +149,7 @@ func TestCreateArticle(t *testing.T) {
 	assert.Equal(t, "Test Article", title)
 
 	// verify in Elastic
+	relayOutbox(t)
 	res, err := es.Get().
 		Index("articles").
 		Id(id.String()).
@@ -131,18 +170,18 @@ func TestUpdateArticle(t *testing.T) {
 	assert.NoError(t, err)
 
 	input := articles.ArticleInput{
-		Title:    "Old Title",
-		Body:     "Old Body",
+		Title: "Old Title",
+		Body:  "Old Body",
 	}
 	id, err := mutation.CreateArticle(ctx, &input, authorID)
 	assert.NoError(t, err)
 
 	mutation2 := newMutation()
 	updateInput := articles.ArticleInput{
-		Title:    "New Title",
-		Body:     "New Body",
+		Title: "New Title",
+		Body:  "New Body",
 	}
-	_, err = mutation2.UpdateArticle(ctx, &updateInput, *id, authorID)
+	_, err = mutation2.UpdateArticle(ctx, &updateInput, *id, authorID, 1)
 	assert.NoError(t, err)
 
 	var title string
@@ -150,6 +189,7 @@ func TestUpdateArticle(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "New Title", title)
 
+	relayOutbox(t)
 	res, err := es.Get().
 		Index("articles").
 		Id(id.String()).
@@ -168,17 +208,32 @@ func TestGetArticleByID(t *testing.T) {
 		authorID, "Rani", "rani@example.com",
 	)
 	assert.NoError(t, err)
+	verifyEmails(t)
 
 	input := articles.ArticleInput{
-		Title:    "Get Title",
-		Body:     "Get Body",
+		Title: "Get Title",
+		Body:  "Get Body",
 	}
 	id, err := mutation.CreateArticle(ctx, &input, authorID)
 	assert.NoError(t, err)
-	article, err := mutation.GetArticleByID(ctx, *id)
+	article, err := mutation.GetArticleByID(ctx, *id, authorID)
 	assert.NoError(t, err)
 	assert.Equal(t, "Get Title", article.Title)
 	assert.Equal(t, "Rani", article.Author.Name)
+
+	_, err = mutation.GetArticleByID(ctx, *id, uuid.Nil)
+	assert.ErrorContains(t, err, "NOT_FOUND")
+	_, err = mutation.GetArticleByID(ctx, *id, uuid.New())
+	assert.ErrorContains(t, err, "NOT_FOUND")
+
+	_, err = mutation.PublishArticle(ctx, *id, authorID)
+	assert.NoError(t, err)
+	article, err = mutation.GetArticleByID(ctx, *id, uuid.Nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, article.Version)
+
+	_, err = mutation.GetArticleByID(ctx, uuid.New(), authorID)
+	assert.ErrorContains(t, err, "NOT_FOUND")
 }
 
 func TestGetArticleWithAuthorByID(t *testing.T) {
@@ -193,18 +248,899 @@ func TestGetArticleWithAuthorByID(t *testing.T) {
 	require.NoError(t, err)
 
 	input := articles.ArticleInput{
-		Title:    "Siti's Article",
-		Body:     "Content from Siti",
+		Title: "Siti's Article",
+		Body:  "Content from Siti",
 	}
 	_, err = mutation.CreateArticle(ctx, &input, authorID)
 	require.NoError(t, err)
 
-	_, _ = es.Refresh("articles").Do(ctx)
+	relayOutbox(t)
 
-	result, err := mutation.GetArticleWithAuthorByID(ctx, authorID)
+	result, err := mutation.GetArticleWithAuthorByID(ctx, authorID, authorID, articles.PageRequest{})
 	require.NoError(t, err)
 
 	assert.Equal(t, "Siti", result.Author.Name)
 	assert.Len(t, result.Article, 1)
 	assert.Equal(t, "Siti's Article", result.Article[0].Title)
 }
+
+func TestGetArticleWithAuthorByIDPagination(t *testing.T) {
+	mutation := newMutation()
+	cleanDB()
+
+	authorID := uuid.New()
+	_, err := testDB.Exec(
+		`INSERT INTO authors (id, name, email) VALUES ($1, $2, $3)`,
+		authorID, "Budi", "budi@example.com",
+	)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		input := articles.ArticleInput{
+			Title: fmt.Sprintf("Budi's Article %d", i),
+			Body:  "Content from Budi",
+		}
+		_, err = mutation.CreateArticle(ctx, &input, authorID)
+		require.NoError(t, err)
+	}
+
+	relayOutbox(t)
+
+	first, err := mutation.GetArticleWithAuthorByID(ctx, authorID, authorID, articles.PageRequest{Limit: 2})
+	require.NoError(t, err)
+	assert.Len(t, first.Article, 2)
+	require.NotEmpty(t, first.NextCursor)
+
+	second, err := mutation.GetArticleWithAuthorByID(ctx, authorID, authorID, articles.PageRequest{Limit: 2, Cursor: first.NextCursor})
+	require.NoError(t, err)
+	assert.Len(t, second.Article, 1)
+	assert.Empty(t, second.NextCursor)
+	assert.NotEqual(t, first.Article[0].ID, second.Article[0].ID)
+	assert.NotEqual(t, first.Article[1].ID, second.Article[0].ID)
+
+	// a newest-first cursor does not fit a best-match search
+	_, err = mutation.GetArticleByKeyWord(ctx, articles.SearchRequest{Keyword: "budi"}, authorID, articles.PageRequest{Limit: 2, Cursor: first.NextCursor})
+	assert.ErrorContains(t, err, "INVALID_INPUT")
+}
+
+func TestGetArticleByAuthorNamePagination(t *testing.T) {
+	mutation := newMutation()
+	cleanDB()
+
+	sitiID := uuid.New()
+	situID := uuid.New()
+	_, err := testDB.Exec(
+		`INSERT INTO authors (id, name, email) VALUES ($1, $2, $3), ($4, $5, $6)`,
+		sitiID, "Siti", "siti@example.com",
+		situID, "Situmorang", "situmorang@example.com",
+	)
+	require.NoError(t, err)
+	verifyEmails(t)
+
+	for _, authorID := range []uuid.UUID{sitiID, situID} {
+		id, err := mutation.CreateArticle(ctx, &articles.ArticleInput{Title: "Artikel", Body: "Isi"}, authorID)
+		require.NoError(t, err)
+		_, err = mutation.PublishArticle(ctx, *id, authorID)
+		require.NoError(t, err)
+	}
+	relayOutbox(t)
+
+	first, err := mutation.GetArticleByAuthorName(ctx, "sit", uuid.Nil, articles.PageRequest{Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, first.Authors, 1)
+	require.Len(t, first.Authors[0].Article, 1)
+	require.NotEmpty(t, first.NextCursor)
+
+	second, err := mutation.GetArticleByAuthorName(ctx, "sit", uuid.Nil, articles.PageRequest{Limit: 1, Cursor: first.NextCursor})
+	require.NoError(t, err)
+	require.Len(t, second.Authors, 1)
+	require.Len(t, second.Authors[0].Article, 1)
+	assert.NotEqual(t, first.Authors[0].ID, second.Authors[0].ID)
+}
+
+func TestDeleteAndRestoreArticle(t *testing.T) {
+	mutation := newMutation()
+	cleanDB()
+
+	authorID := uuid.New()
+	otherAuthorID := uuid.New()
+	_, err := testDB.Exec(
+		`INSERT INTO authors (id, name, email) VALUES ($1, $2, $3), ($4, $5, $6)`,
+		authorID, "Eka", "eka@example.com",
+		otherAuthorID, "Fajar", "fajar@example.com",
+	)
+	require.NoError(t, err)
+
+	input := articles.ArticleInput{
+		Title: "Deleted Title",
+		Body:  "Deleted Body",
+	}
+	id, err := mutation.CreateArticle(ctx, &input, authorID)
+	require.NoError(t, err)
+
+	err = mutation.DeleteArticle(ctx, *id, otherAuthorID)
+	assert.ErrorContains(t, err, "FORBIDDEN")
+
+	err = mutation.DeleteArticle(ctx, *id, authorID)
+	require.NoError(t, err)
+
+	_, err = mutation.GetArticleByID(ctx, *id, authorID)
+	assert.Error(t, err)
+
+	relayOutbox(t)
+	_, err = es.Get().
+		Index("articles").
+		Id(id.String()).
+		Do(ctx)
+	assert.True(t, elastic.IsNotFound(err))
+
+	restoredID, err := mutation.RestoreArticle(ctx, *id, authorID)
+	require.NoError(t, err)
+	assert.Equal(t, *id, *restoredID)
+
+	article, err := mutation.GetArticleByID(ctx, *id, authorID)
+	require.NoError(t, err)
+	assert.Equal(t, "Deleted Title", article.Title)
+
+	relayOutbox(t)
+	res, err := es.Get().
+		Index("articles").
+		Id(id.String()).
+		Do(ctx)
+	assert.NoError(t, err)
+	assert.True(t, res.Found)
+}
+
+func TestUpdateArticleOwnership(t *testing.T) {
+	mutation := newMutation()
+	cleanDB()
+
+	authorID := uuid.New()
+	otherAuthorID := uuid.New()
+	_, err := testDB.Exec(
+		`INSERT INTO authors (id, name, email) VALUES ($1, $2, $3), ($4, $5, $6)`,
+		authorID, "Gita", "gita@example.com",
+		otherAuthorID, "Hadi", "hadi@example.com",
+	)
+	require.NoError(t, err)
+
+	input := articles.ArticleInput{
+		Title: "Gita's Title",
+		Body:  "Gita's Body",
+	}
+	id, err := mutation.CreateArticle(ctx, &input, authorID)
+	require.NoError(t, err)
+
+	updateInput := articles.ArticleInput{
+		Title: "Hijacked Title",
+		Body:  "Hijacked Body",
+	}
+	_, err = mutation.UpdateArticle(ctx, &updateInput, *id, otherAuthorID, 1)
+	assert.ErrorContains(t, err, "FORBIDDEN")
+
+	var title string
+	err = testDB.Get(&title, "SELECT title FROM articles WHERE id = $1", *id)
+	require.NoError(t, err)
+	assert.Equal(t, "Gita's Title", title)
+
+	missingID := uuid.New()
+	_, err = mutation.UpdateArticle(ctx, &updateInput, missingID, authorID, 1)
+	assert.ErrorContains(t, err, "NOT_FOUND")
+
+	_, err = es.Get().
+		Index("articles").
+		Id(missingID.String()).
+		Do(ctx)
+	assert.True(t, elastic.IsNotFound(err))
+}
+
+func TestArticleStatusLifecycle(t *testing.T) {
+	mutation := newMutation()
+	cleanDB()
+
+	authorID := uuid.New()
+	_, err := testDB.Exec(
+		`INSERT INTO authors (id, name, email) VALUES ($1, $2, $3)`,
+		authorID, "Indah", "indah@example.com",
+	)
+	require.NoError(t, err)
+	verifyEmails(t)
+
+	input := articles.ArticleInput{
+		Title: "Indah's Draft",
+		Body:  "Not public yet",
+	}
+	id, err := mutation.CreateArticle(ctx, &input, authorID)
+	require.NoError(t, err)
+
+	relayOutbox(t)
+
+	anonymous, err := mutation.GetArticleWithAuthorByID(ctx, authorID, uuid.Nil, articles.PageRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, anonymous.Article)
+
+	own, err := mutation.GetArticleWithAuthorByID(ctx, authorID, authorID, articles.PageRequest{})
+	require.NoError(t, err)
+	require.Len(t, own.Article, 1)
+	assert.Equal(t, articles.StatusDraft, own.Article[0].Status)
+
+	_, err = mutation.PublishArticle(ctx, *id, authorID)
+	require.NoError(t, err)
+
+	relayOutbox(t)
+
+	anonymous, err = mutation.GetArticleWithAuthorByID(ctx, authorID, uuid.Nil, articles.PageRequest{})
+	require.NoError(t, err)
+	require.Len(t, anonymous.Article, 1)
+	assert.Equal(t, articles.StatusPublished, anonymous.Article[0].Status)
+
+	_, err = mutation.ArchiveArticle(ctx, *id, authorID)
+	require.NoError(t, err)
+
+	_, err = mutation.PublishArticle(ctx, *id, authorID)
+	assert.ErrorContains(t, err, "Invalid status transition")
+}
+
+func TestScheduledPublishing(t *testing.T) {
+	mutation := newMutation()
+	cleanDB()
+
+	authorID := uuid.New()
+	_, err := testDB.Exec(
+		`INSERT INTO authors (id, name, email) VALUES ($1, $2, $3)`,
+		authorID, "Joko", "joko@example.com",
+	)
+	require.NoError(t, err)
+	verifyEmails(t)
+
+	input := articles.ArticleInput{
+		Title: "Joko's Scheduled Article",
+		Body:  "Published later",
+	}
+	id, err := mutation.CreateArticle(ctx, &input, authorID)
+	require.NoError(t, err)
+
+	_, err = mutation.ScheduleArticle(ctx, *id, authorID, time.Now().Add(-time.Minute))
+	assert.ErrorContains(t, err, "INVALID_INPUT")
+
+	_, err = mutation.ScheduleArticle(ctx, *id, authorID, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	scheduler := articles.NewPublishScheduler(articles.NewArticleRepo(ctx, testDB), testDB, time.Minute, 10)
+
+	published, err := scheduler.PublishDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, published)
+
+	_, err = testDB.Exec(`UPDATE articles SET publish_at = $2 WHERE id = $1`, *id, time.Now().Add(-time.Second))
+	require.NoError(t, err)
+
+	published, err = scheduler.PublishDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, published)
+
+	var status string
+	err = testDB.Get(&status, "SELECT status FROM articles WHERE id = $1", *id)
+	require.NoError(t, err)
+	assert.Equal(t, string(articles.StatusPublished), status)
+
+	relayOutbox(t)
+
+	anonymous, err := mutation.GetArticleWithAuthorByID(ctx, authorID, uuid.Nil, articles.PageRequest{})
+	require.NoError(t, err)
+	assert.Len(t, anonymous.Article, 1)
+}
+
+func TestArticleRevisions(t *testing.T) {
+	mutation := newMutation()
+	cleanDB()
+
+	authorID := uuid.New()
+	_, err := testDB.Exec(
+		`INSERT INTO authors (id, name, email) VALUES ($1, $2, $3)`,
+		authorID, "Rina", "rina@example.com",
+	)
+	require.NoError(t, err)
+
+	input := articles.ArticleInput{
+		Title: "Rina's Article",
+		Body:  "first line\nsecond line",
+	}
+	id, err := mutation.CreateArticle(ctx, &input, authorID)
+	require.NoError(t, err)
+
+	_, err = mutation.UpdateArticle(ctx, &articles.ArticleInput{
+		Title: "Rina's Article",
+		Body:  "first line\nchanged line",
+	}, *id, authorID, 1)
+	require.NoError(t, err)
+
+	revisions, err := mutation.GetArticleRevisions(ctx, *id, authorID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, 2, revisions[0].Revision)
+
+	revisionDiff, err := mutation.GetArticleRevision(ctx, *id, 1, authorID)
+	require.NoError(t, err)
+	assert.Equal(t, []articles.DiffLine{
+		{Op: articles.DiffEqual, Text: "first line"},
+		{Op: articles.DiffDelete, Text: "second line"},
+		{Op: articles.DiffInsert, Text: "changed line"},
+	}, revisionDiff.BodyDiff)
+
+	_, err = mutation.GetArticleRevisions(ctx, *id, uuid.New())
+	assert.ErrorContains(t, err, "FORBIDDEN")
+
+	_, err = mutation.GetArticleRevision(ctx, *id, 99, authorID)
+	assert.ErrorContains(t, err, "NOT_FOUND")
+
+	_, err = mutation.RestoreArticleRevision(ctx, *id, 1, authorID)
+	require.NoError(t, err)
+
+	var body string
+	err = testDB.Get(&body, "SELECT body FROM articles WHERE id = $1", *id)
+	require.NoError(t, err)
+	assert.Equal(t, input.Body, body)
+
+	revisions, err = mutation.GetArticleRevisions(ctx, *id, authorID)
+	require.NoError(t, err)
+	assert.Len(t, revisions, 3)
+}
+
+func TestUpdateArticleVersionConflict(t *testing.T) {
+	mutation := newMutation()
+	cleanDB()
+
+	authorID := uuid.New()
+	_, err := testDB.Exec(
+		`INSERT INTO authors (id, name, email) VALUES ($1, $2, $3)`,
+		authorID, "Sari", "sari@example.com",
+	)
+	require.NoError(t, err)
+
+	input := articles.ArticleInput{
+		Title: "Sari's Article",
+		Body:  "Original body",
+	}
+	id, err := mutation.CreateArticle(ctx, &input, authorID)
+	require.NoError(t, err)
+
+	_, err = mutation.UpdateArticle(ctx, &input, *id, authorID, 0)
+	assert.ErrorContains(t, err, "PRECONDITION_REQUIRED")
+
+	first, err := mutation.UpdateArticle(ctx, &articles.ArticleInput{
+		Title: "Sari's Article",
+		Body:  "First editor",
+	}, *id, authorID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, first.Version)
+
+	_, err = mutation.UpdateArticle(ctx, &articles.ArticleInput{
+		Title: "Sari's Article",
+		Body:  "Second editor",
+	}, *id, authorID, 1)
+	assert.ErrorContains(t, err, "PRECONDITION_FAILED")
+
+	var body string
+	err = testDB.Get(&body, "SELECT body FROM articles WHERE id = $1", *id)
+	require.NoError(t, err)
+	assert.Equal(t, "First editor", body)
+
+	relayOutbox(t)
+	res, err := es.Get().
+		Index("articles").
+		Id(id.String()).
+		Do(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, res.Version)
+	assert.Equal(t, int64(2), *res.Version)
+}
+
+func TestOutboxRelay(t *testing.T) {
+	mutation := newMutation()
+	cleanDB()
+
+	authorID := uuid.New()
+	_, err := testDB.Exec(
+		`INSERT INTO authors (id, name, email) VALUES ($1, $2, $3)`,
+		authorID, "Wati", "wati@example.com",
+	)
+	require.NoError(t, err)
+
+	input := articles.ArticleInput{
+		Title: "Wati's Article",
+		Body:  "Relayed later",
+	}
+	id, err := mutation.CreateArticle(ctx, &input, authorID)
+	require.NoError(t, err)
+
+	// Nothing reaches Elasticsearch until the relay runs.
+	_, err = es.Get().
+		Index("articles").
+		Id(id.String()).
+		Do(ctx)
+	assert.True(t, elastic.IsNotFound(err))
+
+	relay := articles.NewOutboxRelay(articles.NewArticleRepo(ctx, testDB), articles.NewArticleIndexer(es), time.Second, 100, 3)
+	processed, err := relay.RelayPending(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, processed)
+
+	res, err := es.Get().
+		Index("articles").
+		Id(id.String()).
+		Do(ctx)
+	require.NoError(t, err)
+	assert.True(t, res.Found)
+
+	// An event the indexer cannot apply is retried and finally dead-lettered.
+	_, err = testDB.Exec(
+		`INSERT INTO outbox (id, aggregate_id, event_type, payload) VALUES ($1, $2, 'article.unknown', '{}')`,
+		uuid.New(), *id,
+	)
+	require.NoError(t, err)
+	for attempt := 0; attempt < 3; attempt++ {
+		_, err = testDB.Exec(`UPDATE outbox SET next_attempt_at = now() - interval '1 second'`)
+		require.NoError(t, err)
+		_, err = relay.RelayPending(ctx)
+		require.NoError(t, err)
+	}
+
+	stats, err := relay.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.Relayed)
+	assert.Equal(t, int64(2), stats.Retried)
+	assert.Equal(t, int64(1), stats.DeadLettered)
+	assert.Equal(t, int64(0), stats.Pending)
+	assert.Equal(t, int64(1), stats.Dead)
+}
+
+func TestReindex(t *testing.T) {
+	mutation := newMutation()
+	cleanDB()
+
+	authorID := uuid.New()
+	_, err := testDB.Exec(
+		`INSERT INTO authors (id, name, email) VALUES ($1, $2, $3)`,
+		authorID, "Yusuf", "yusuf@example.com",
+	)
+	require.NoError(t, err)
+
+	var idList []uuid.UUID
+	for i := 0; i < 5; i++ {
+		input := articles.ArticleInput{
+			Title: fmt.Sprintf("Yusuf's Article %d", i),
+			Body:  "Reindexed",
+		}
+		id, err := mutation.CreateArticle(ctx, &input, authorID)
+		require.NoError(t, err)
+		idList = append(idList, *id)
+	}
+	err = mutation.DeleteArticle(ctx, idList[0], authorID)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	reindexer := articles.NewReindexer(articles.NewArticleRepo(ctx, testDB), articles.NewArticleIndexer(es), 2, &out)
+	require.NoError(t, reindexer.Run(ctx))
+	assert.Contains(t, out.String(), "indexed 4/4 articles")
+
+	aliases, err := es.Aliases().Index("_all").Do(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"articles_v2"}, aliases.IndicesByAlias(articles.ArticleIndexAlias))
+
+	_, _ = es.Refresh("articles").Do(ctx)
+	count, err := es.Count("articles").Do(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), count)
+
+	res, err := es.Get().
+		Index("articles").
+		Id(idList[1].String()).
+		Do(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, string(res.Source), `"name":"Yusuf"`)
+
+	var completed int
+	err = testDB.Get(&completed, "SELECT COUNT(*) FROM reindex_checkpoints WHERE index_name = 'articles_v2' AND completed_at IS NOT NULL")
+	require.NoError(t, err)
+	assert.Equal(t, 1, completed)
+}
+
+func TestSearchArticleRelevance(t *testing.T) {
+	mutation := newMutation()
+	cleanDB()
+
+	authorID := uuid.New()
+	_, err := testDB.Exec(
+		`INSERT INTO authors (id, name, email) VALUES ($1, $2, $3)`,
+		authorID, "Hana", "hana@example.com",
+	)
+	require.NoError(t, err)
+
+	inTitle, err := mutation.CreateArticle(ctx, &articles.ArticleInput{
+		Title: "Belajar Golang untuk Pemula",
+		Body:  "Panduan singkat menulis program pertama",
+	}, authorID)
+	require.NoError(t, err)
+	_, err = mutation.CreateArticle(ctx, &articles.ArticleInput{
+		Title: "Catatan Mingguan",
+		Body:  "Minggu ini tim kami juga mulai belajar, termasuk sedikit golang",
+	}, authorID)
+	require.NoError(t, err)
+	relayOutbox(t)
+
+	result, err := mutation.GetArticleByKeyWord(ctx, articles.SearchRequest{Keyword: "golang"}, authorID, articles.PageRequest{})
+	require.NoError(t, err)
+	require.Len(t, result.Articles, 2)
+	assert.Equal(t, *inTitle, result.Articles[0].ID)
+	require.NotNil(t, result.Articles[0].Score)
+	assert.Greater(t, *result.Articles[0].Score, *result.Articles[1].Score)
+
+	// typo
+	result, err = mutation.GetArticleByKeyWord(ctx, articles.SearchRequest{Keyword: "golnag"}, authorID, articles.PageRequest{})
+	require.NoError(t, err)
+	assert.Len(t, result.Articles, 2)
+
+	// exact phrase
+	result, err = mutation.GetArticleByKeyWord(ctx, articles.SearchRequest{Keyword: `"belajar golang"`}, authorID, articles.PageRequest{})
+	require.NoError(t, err)
+	require.Len(t, result.Articles, 1)
+	assert.Equal(t, *inTitle, result.Articles[0].ID)
+
+	// anonymous callers do not see drafts
+	result, err = mutation.GetArticleByKeyWord(ctx, articles.SearchRequest{Keyword: "golang"}, uuid.Nil, articles.PageRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, result.Articles)
+}
+
+func TestSearchArticleHighlight(t *testing.T) {
+	mutation := newMutation()
+	cleanDB()
+
+	authorID := uuid.New()
+	_, err := testDB.Exec(
+		`INSERT INTO authors (id, name, email) VALUES ($1, $2, $3)`,
+		authorID, "Lala", "lala@example.com",
+	)
+	require.NoError(t, err)
+
+	_, err = mutation.CreateArticle(ctx, &articles.ArticleInput{
+		Title: "Resep Rendang",
+		Body:  strings.Repeat("Daging dimasak perlahan. ", 50) + "Rendang siap disajikan.",
+	}, authorID)
+	require.NoError(t, err)
+	relayOutbox(t)
+
+	result, err := mutation.GetArticleByKeyWord(ctx, articles.SearchRequest{
+		Keyword: "rendang",
+		Fields:  []string{"title"},
+		Highlight: articles.HighlightOptions{
+			FragmentSize: 50,
+			PreTag:       "<mark>",
+			PostTag:      "</mark>",
+		},
+	}, authorID, articles.PageRequest{})
+	require.NoError(t, err)
+	require.Len(t, result.Articles, 1)
+
+	article := result.Articles[0]
+	assert.Empty(t, article.Body)
+	assert.Equal(t, []string{"Resep <mark>Rendang</mark>"}, article.Highlights["title"])
+	assert.Contains(t, article.Snippet, "<mark>Rendang</mark>")
+	assert.Less(t, len(article.Snippet), 100)
+
+	_, err = mutation.GetArticleByKeyWord(ctx, articles.SearchRequest{
+		Keyword: "rendang",
+		Fields:  []string{"password"},
+	}, authorID, articles.PageRequest{})
+	assert.ErrorContains(t, err, "INVALID_INPUT")
+
+	// a missing tag falls back to its own default
+	result, err = mutation.GetArticleByKeyWord(ctx, articles.SearchRequest{
+		Keyword:   "rendang",
+		Highlight: articles.HighlightOptions{PreTag: "<em>"},
+	}, authorID, articles.PageRequest{})
+	require.NoError(t, err)
+	require.Len(t, result.Articles, 1)
+	assert.Equal(t, []string{"Resep <em>Rendang</em>"}, result.Articles[0].Highlights["title"])
+
+	// tags are not free HTML
+	_, err = mutation.GetArticleByKeyWord(ctx, articles.SearchRequest{
+		Keyword:   "rendang",
+		Highlight: articles.HighlightOptions{PreTag: "<img src=x onerror=alert(1)>", PostTag: "</em>"},
+	}, authorID, articles.PageRequest{})
+	assert.ErrorContains(t, err, "INVALID_INPUT")
+}
+
+func TestSearchArticleFacets(t *testing.T) {
+	mutation := newMutation()
+	cleanDB()
+
+	hanaID, budiID := uuid.New(), uuid.New()
+	_, err := testDB.Exec(
+		`INSERT INTO authors (id, name, email) VALUES ($1, $2, $3), ($4, $5, $6)`,
+		hanaID, "Hana", "hana@example.com",
+		budiID, "Budi", "budi@example.com",
+	)
+	require.NoError(t, err)
+	verifyEmails(t)
+
+	_, err = mutation.CreateArticle(ctx, &articles.ArticleInput{Title: "Draft Hana", Body: "Belum selesai"}, hanaID)
+	require.NoError(t, err)
+	published, err := mutation.CreateArticle(ctx, &articles.ArticleInput{Title: "Artikel Hana", Body: "Sudah terbit"}, hanaID)
+	require.NoError(t, err)
+	_, err = mutation.PublishArticle(ctx, *published, hanaID)
+	require.NoError(t, err)
+	budiArticle, err := mutation.CreateArticle(ctx, &articles.ArticleInput{Title: "Artikel Budi", Body: "Sudah terbit"}, budiID)
+	require.NoError(t, err)
+	_, err = mutation.PublishArticle(ctx, *budiArticle, budiID)
+	require.NoError(t, err)
+	relayOutbox(t)
+
+	result, err := mutation.GetArticleByKeyWord(ctx, articles.SearchRequest{}, hanaID, articles.PageRequest{})
+	require.NoError(t, err)
+	assert.Len(t, result.Articles, 3)
+	require.NotNil(t, result.Facets)
+	assert.Equal(t, []articles.FacetBucket{
+		{Key: hanaID.String(), Name: "Hana", Count: 2},
+		{Key: budiID.String(), Name: "Budi", Count: 1},
+	}, result.Facets.Authors)
+	assert.Equal(t, []articles.FacetBucket{
+		{Key: "published", Count: 2},
+		{Key: "draft", Count: 1},
+	}, result.Facets.Statuses)
+	assert.Equal(t, []articles.FacetBucket{
+		{Key: time.Now().UTC().Format("2006-01"), Count: 3},
+	}, result.Facets.Months)
+
+	result, err = mutation.GetArticleByKeyWord(ctx, articles.SearchRequest{
+		Keyword:   "artikel",
+		AuthorIDs: []uuid.UUID{budiID},
+	}, hanaID, articles.PageRequest{})
+	require.NoError(t, err)
+	require.Len(t, result.Articles, 1)
+	assert.Equal(t, *budiArticle, result.Articles[0].ID)
+	assert.Len(t, result.Facets.Authors, 1)
+
+	result, err = mutation.GetArticleByKeyWord(ctx, articles.SearchRequest{
+		Statuses: []articles.Status{articles.StatusPublished},
+	}, hanaID, articles.PageRequest{})
+	require.NoError(t, err)
+	assert.Len(t, result.Articles, 2)
+
+	from := time.Now().Add(time.Hour)
+	result, err = mutation.GetArticleByKeyWord(ctx, articles.SearchRequest{From: &from}, hanaID, articles.PageRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, result.Articles)
+
+	_, err = mutation.GetArticleByKeyWord(ctx, articles.SearchRequest{
+		Statuses: []articles.Status{"deleted"},
+	}, hanaID, articles.PageRequest{})
+	assert.ErrorContains(t, err, "INVALID_INPUT")
+}
+
+func TestSuggestArticle(t *testing.T) {
+	mutation := newMutation()
+	cleanDB()
+
+	authorID := uuid.New()
+	_, err := testDB.Exec(
+		`INSERT INTO authors (id, name, email) VALUES ($1, $2, $3)`,
+		authorID, "Bella", "bella@example.com",
+	)
+	require.NoError(t, err)
+
+	articleID, err := mutation.CreateArticle(ctx, &articles.ArticleInput{
+		Title: "Belajar Golang untuk Pemula",
+		Body:  "Panduan singkat",
+	}, authorID)
+	require.NoError(t, err)
+	_, err = mutation.CreateArticle(ctx, &articles.ArticleInput{
+		Title: "Catatan Mingguan",
+		Body:  "Tidak ada hubungannya",
+	}, authorID)
+	require.NoError(t, err)
+	relayOutbox(t)
+
+	result, err := mutation.SuggestArticle(ctx, "bel", authorID, 0)
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	suggested := map[articles.SuggestionType]*articles.Suggestion{}
+	for _, suggestion := range result {
+		suggested[suggestion.Type] = suggestion
+	}
+	require.Contains(t, suggested, articles.SuggestionArticle)
+	require.Contains(t, suggested, articles.SuggestionAuthor)
+	assert.Equal(t, *articleID, suggested[articles.SuggestionArticle].ID)
+	assert.Equal(t, "Bella", suggested[articles.SuggestionAuthor].Text)
+	assert.GreaterOrEqual(t, result[0].Score, result[1].Score)
+
+	result, err = mutation.SuggestArticle(ctx, "belajar gol", authorID, 1)
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, *articleID, result[0].ID)
+
+	result, err = mutation.SuggestArticle(ctx, "b", authorID, 0)
+	require.NoError(t, err)
+	assert.Empty(t, result)
+}
+
+func TestGetRelatedArticles(t *testing.T) {
+	mutation := newMutation()
+	cleanDB()
+
+	authorID := uuid.New()
+	_, err := testDB.Exec(
+		`INSERT INTO authors (id, name, email) VALUES ($1, $2, $3)`,
+		authorID, "Hana", "hana@example.com",
+	)
+	require.NoError(t, err)
+	verifyEmails(t)
+
+	create := func(title, body string, publish bool) uuid.UUID {
+		id, err := mutation.CreateArticle(ctx, &articles.ArticleInput{Title: title, Body: body}, authorID)
+		require.NoError(t, err)
+		if publish {
+			_, err = mutation.PublishArticle(ctx, *id, authorID)
+			require.NoError(t, err)
+		}
+		return *id
+	}
+	source := create("Belajar Golang", "Goroutine dan channel di golang untuk concurrency", true)
+	similar := create("Concurrency di Golang", "Memakai goroutine dan channel dengan aman", true)
+	create("Resep Rendang", "Daging dimasak dengan santan dan bumbu", true)
+	draft := create("Golang Lanjutan", "Goroutine, channel dan concurrency lanjutan", false)
+	relayOutbox(t)
+
+	related, err := mutation.GetRelatedArticles(ctx, source, uuid.Nil, 0)
+	require.NoError(t, err)
+	require.Len(t, related, 1)
+	assert.Equal(t, similar, related[0].ID)
+	require.NotNil(t, related[0].Author)
+	assert.Equal(t, "Hana", related[0].Author.Name)
+
+	_, err = mutation.GetRelatedArticles(ctx, draft, uuid.Nil, 0)
+	assert.ErrorContains(t, err, "NOT_FOUND")
+
+	related, err = mutation.GetRelatedArticles(ctx, draft, authorID, 0)
+	require.NoError(t, err)
+	assert.Len(t, related, 2)
+}
+
+// sensitiveFields must never appear anywhere in an API response.
+var sensitiveFields = []string{"password", "email", "deleted_at"}
+
+func assertNoSensitiveFields(t *testing.T, response interface{}) {
+	t.Helper()
+	raw, err := json.Marshal(response)
+	require.NoError(t, err)
+	var decoded interface{}
+	require.NoError(t, json.Unmarshal(raw, &decoded))
+
+	var walk func(path string, value interface{})
+	walk = func(path string, value interface{}) {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			for key, child := range v {
+				for _, field := range sensitiveFields {
+					assert.NotEqual(t, field, key, "sensitive field at %s.%s", path, key)
+				}
+				walk(path+"."+key, child)
+			}
+		case []interface{}:
+			for n, child := range v {
+				walk(fmt.Sprintf("%s[%d]", path, n), child)
+			}
+		}
+	}
+	walk("$", decoded)
+}
+
+func TestResponsesHideSensitiveFields(t *testing.T) {
+	mutation := newMutation()
+	cleanDB()
+
+	authorID := uuid.New()
+	_, err := testDB.Exec(
+		`INSERT INTO authors (id, name, email, password) VALUES ($1, $2, $3, $4)`,
+		authorID, "Rani", "rani@example.com", "$2a$10$hash",
+	)
+	require.NoError(t, err)
+	verifyEmails(t)
+
+	id, err := mutation.CreateArticle(ctx, &articles.ArticleInput{Title: "Judul", Body: "Isi"}, authorID)
+	require.NoError(t, err)
+	_, err = mutation.PublishArticle(ctx, *id, authorID)
+	require.NoError(t, err)
+	relayOutbox(t)
+
+	article, err := mutation.GetArticleByID(ctx, *id, authorID)
+	require.NoError(t, err)
+	require.NotEmpty(t, article.Author.Email)
+	assertNoSensitiveFields(t, articles.NewArticleResponse(article))
+
+	page, err := mutation.GetArticleByKeyWord(ctx, articles.SearchRequest{Keyword: "judul"}, authorID, articles.PageRequest{})
+	require.NoError(t, err)
+	require.Len(t, page.Articles, 1)
+	assertNoSensitiveFields(t, articles.NewArticlePageResponse(page))
+
+	withAuthor, err := mutation.GetArticleWithAuthorByID(ctx, authorID, authorID, articles.PageRequest{})
+	require.NoError(t, err)
+	assertNoSensitiveFields(t, articles.NewArticleWithAuthorResponse(withAuthor))
+
+	byName, err := mutation.GetArticleByAuthorName(ctx, "Rani", authorID, articles.PageRequest{})
+	require.NoError(t, err)
+	assertNoSensitiveFields(t, articles.NewArticleWithAuthorPageResponse(byName))
+
+	// the domain struct itself must not leak the hash either
+	raw, err := json.Marshal(authors.Author{Password: "$2a$10$hash"})
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), "hash")
+}
+
+func TestEditorCanEditAnyArticle(t *testing.T) {
+	mutation := newMutation()
+	cleanDB()
+
+	authorID := uuid.New()
+	editorID := uuid.New()
+	_, err := testDB.Exec(
+		`INSERT INTO authors (id, name, email, role) VALUES ($1, $2, $3, 'author'), ($4, $5, $6, 'editor')`,
+		authorID, "Gita", "gita@example.com",
+		editorID, "Eka", "eka@example.com",
+	)
+	require.NoError(t, err)
+	verifyEmails(t)
+
+	id, err := mutation.CreateArticle(ctx, &articles.ArticleInput{Title: "Draft", Body: "Isi"}, authorID)
+	require.NoError(t, err)
+
+	updated, err := mutation.UpdateArticle(ctx, &articles.ArticleInput{Title: "Edited", Body: "Isi"}, *id, editorID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, authorID, updated.AuthorID)
+
+	// the draft an editor can edit is one they can read
+	draft, err := mutation.GetArticleByID(ctx, *id, editorID)
+	require.NoError(t, err)
+	assert.Equal(t, "Edited", draft.Title)
+	_, err = mutation.GetArticleByID(ctx, *id, uuid.Nil)
+	assert.ErrorContains(t, err, "NOT_FOUND")
+
+	revisions, err := mutation.GetArticleRevisions(ctx, *id, editorID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, editorID, revisions[0].EditedBy)
+
+	_, err = mutation.PublishArticle(ctx, *id, editorID)
+	require.NoError(t, err)
+
+	// the role is checked on every request, so a demoted editor loses access
+	_, err = testDB.Exec(`UPDATE authors SET role = 'author' WHERE id = $1`, editorID)
+	require.NoError(t, err)
+	_, err = mutation.ArchiveArticle(ctx, *id, editorID)
+	assert.ErrorContains(t, err, "FORBIDDEN")
+}
+
+func TestPublishNeedsVerifiedEmail(t *testing.T) {
+	cleanDB()
+	mutation := newMutation()
+
+	authorID := uuid.New()
+	_, err := testDB.Exec(
+		`INSERT INTO authors (id, name, email) VALUES ($1, $2, $3)`,
+		authorID, "Wulan", "wulan@example.com",
+	)
+	require.NoError(t, err)
+
+	// drafts are fine, publishing and scheduling are not
+	id, err := mutation.CreateArticle(ctx, &articles.ArticleInput{Title: "Draft", Body: "Not yet"}, authorID)
+	require.NoError(t, err)
+	_, err = mutation.PublishArticle(ctx, *id, authorID)
+	assert.ErrorContains(t, err, "Verify your email before publishing")
+	_, err = mutation.ScheduleArticle(ctx, *id, authorID, time.Now().Add(time.Hour))
+	assert.ErrorContains(t, err, "Verify your email before publishing")
+
+	verifyEmails(t)
+	_, err = mutation.PublishArticle(ctx, *id, authorID)
+	assert.NoError(t, err)
+}
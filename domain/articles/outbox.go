@@ -0,0 +1,219 @@
+package articles
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/afif-musyayyidin/hertz-boilerplate/domain/authors"
+	"github.com/afif-musyayyidin/hertz-boilerplate/domain/infra/logger"
+	"github.com/google/uuid"
+)
+
+type OutboxEventType string
+
+const (
+	EventArticleUpsert OutboxEventType = "article.upsert"
+	EventArticleDelete OutboxEventType = "article.delete"
+	// EventAuthorSync is written by the authors package; its aggregate is the
+	// author.
+	EventAuthorSync OutboxEventType = authors.EventAuthorSync
+)
+
+const (
+	OutboxStatusPending = "pending"
+	OutboxStatusDead    = "dead"
+)
+
+// Failed events are retried after outboxBaseBackoff * 2^(attempts-1), capped
+// at outboxMaxBackoff.
+const (
+	outboxBaseBackoff = time.Second
+	outboxMaxBackoff  = 10 * time.Minute
+)
+
+// outboxDispatchTimeout bounds the Elasticsearch work of one event. A batch
+// is claimed for as long as all of its events can take, so a relay that dies
+// mid-batch only delays them.
+const outboxDispatchTimeout = 10 * time.Second
+
+// OutboxEvent is an index change written in the same transaction as the
+// Postgres change it describes. Relayed events are deleted; events that keep
+// failing end up in the dead status.
+type OutboxEvent struct {
+	ID            uuid.UUID       `db:"id"`
+	Seq           int64           `db:"seq"`
+	AggregateID   uuid.UUID       `db:"aggregate_id"`
+	EventType     OutboxEventType `db:"event_type"`
+	Payload       []byte          `db:"payload"`
+	Status        string          `db:"status"`
+	Attempts      int             `db:"attempts"`
+	NextAttemptAt time.Time       `db:"next_attempt_at"`
+	LastError     *string         `db:"last_error"`
+	CreatedAt     time.Time       `db:"created_at"`
+}
+
+type OutboxStats struct {
+	Relayed      int64 `json:"relayed"`
+	Retried      int64 `json:"retried"`
+	DeadLettered int64 `json:"dead_lettered"`
+	Pending      int64 `json:"pending"`
+	Dead         int64 `json:"dead"`
+}
+
+// OutboxRelay drains the outbox into the ArticleIndexer. Events are claimed
+// with FOR UPDATE SKIP LOCKED in a statement of their own, so several
+// replicas can run a relay and no lock is held while Elasticsearch is called.
+type OutboxRelay struct {
+	repo        ArticleRepository
+	index       ArticleIndexer
+	interval    time.Duration
+	batchSize   int
+	maxAttempts int
+
+	relayed      atomic.Int64
+	retried      atomic.Int64
+	deadLettered atomic.Int64
+}
+
+func NewOutboxRelay(repo ArticleRepository, index ArticleIndexer, interval time.Duration, batchSize int, maxAttempts int) *OutboxRelay {
+	return &OutboxRelay{
+		repo:        repo,
+		index:       index,
+		interval:    interval,
+		batchSize:   batchSize,
+		maxAttempts: maxAttempts,
+	}
+}
+
+// Run blocks until ctx is cancelled.
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		r.relayAll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *OutboxRelay) relayAll(ctx context.Context) {
+	for ctx.Err() == nil {
+		processed, err := r.RelayPending(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Debug("outbox relay failed", err)
+			}
+			return
+		}
+		if processed < r.batchSize {
+			return
+		}
+	}
+}
+
+// RelayPending claims one batch of due events, dispatches them and records
+// each outcome, returning how many were processed, successfully or not. An
+// event whose outcome cannot be recorded is dispatched again once its claim
+// runs out; indexing is idempotent.
+func (r *OutboxRelay) RelayPending(ctx context.Context) (int, error) {
+	events, err := r.repo.ClaimPendingOutbox(ctx, r.batchSize, time.Duration(r.batchSize)*outboxDispatchTimeout)
+	if err != nil {
+		return 0, err
+	}
+	for _, event := range events {
+		dispatchCtx, cancel := context.WithTimeout(ctx, outboxDispatchTimeout)
+		err := r.dispatch(dispatchCtx, event)
+		cancel()
+		if err != nil {
+			if err := r.fail(ctx, event, err); err != nil {
+				return 0, err
+			}
+			if event.Status == OutboxStatusDead {
+				r.deadLettered.Add(1)
+			} else {
+				r.retried.Add(1)
+			}
+			continue
+		}
+		if err := r.repo.DeleteOutboxEvent(ctx, event.ID); err != nil {
+			return 0, err
+		}
+		r.relayed.Add(1)
+	}
+	return len(events), nil
+}
+
+// Stats returns the counters of this relay together with the current outbox
+// backlog.
+func (r *OutboxRelay) Stats(ctx context.Context) (*OutboxStats, error) {
+	counts, err := r.repo.CountOutboxByStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &OutboxStats{
+		Relayed:      r.relayed.Load(),
+		Retried:      r.retried.Load(),
+		DeadLettered: r.deadLettered.Load(),
+		Pending:      counts[OutboxStatusPending],
+		Dead:         counts[OutboxStatusDead],
+	}, nil
+}
+
+func (r *OutboxRelay) dispatch(ctx context.Context, event *OutboxEvent) error {
+	switch event.EventType {
+	case EventArticleUpsert:
+		var article Article
+		if err := json.Unmarshal(event.Payload, &article); err != nil {
+			return err
+		}
+		author, err := r.repo.FindAuthorForIndex(ctx, article.AuthorID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		article.Author = author
+		return r.index.Index(ctx, &article)
+	case EventArticleDelete:
+		return r.index.Delete(ctx, event.AggregateID.String())
+	case EventAuthorSync:
+		articles, err := r.repo.FindByAuthorForIndex(ctx, event.AggregateID)
+		if err != nil {
+			return err
+		}
+		return r.index.BulkIndex(ctx, ArticleIndexAlias, articles)
+	default:
+		return fmt.Errorf("unknown outbox event type %q", event.EventType)
+	}
+}
+
+// fail schedules the next attempt of event, or moves it to the dead status
+// once maxAttempts is reached.
+func (r *OutboxRelay) fail(ctx context.Context, event *OutboxEvent, cause error) error {
+	lastError := cause.Error()
+	event.Attempts++
+	event.LastError = &lastError
+	if event.Attempts >= r.maxAttempts {
+		event.Status = OutboxStatusDead
+		logger.Debug(fmt.Sprintf("outbox event %s (%s) dead after %d attempts", event.ID, event.EventType, event.Attempts), cause)
+	}
+	return r.repo.MarkOutboxEventFailed(ctx, event, outboxBackoff(event.Attempts))
+}
+
+func outboxBackoff(attempts int) time.Duration {
+	backoff := outboxBaseBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= outboxMaxBackoff {
+			return outboxMaxBackoff
+		}
+	}
+	return backoff
+}
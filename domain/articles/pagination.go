@@ -0,0 +1,82 @@
+package articles
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+)
+
+const (
+	DefaultPageLimit = 10
+	MaxPageLimit     = 100
+)
+
+// PageRequest describes one page of a search_after based listing. Cursor is
+// the opaque NextCursor of the previous page, empty for the first page.
+type PageRequest struct {
+	Limit  int
+	Cursor string
+}
+
+// ArticlePage is one page of articles. Facets are only set by searches.
+type ArticlePage struct {
+	Articles   []*Article    `json:"articles"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+	Facets     *SearchFacets `json:"facets,omitempty"`
+}
+
+type ArticleWithAuthorPage struct {
+	Authors    []*ArticleWithAuthor `json:"authors"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+}
+
+func (p PageRequest) size() int {
+	if p.Limit <= 0 {
+		return DefaultPageLimit
+	}
+	if p.Limit > MaxPageLimit {
+		return MaxPageLimit
+	}
+	return p.Limit
+}
+
+// cursor is the decoded NextCursor: the sort order of the listing and the
+// sort values of its last hit.
+type cursor struct {
+	Order  string        `json:"s"`
+	Values []interface{} `json:"v"`
+}
+
+// searchAfter decodes the cursor back into the sort values of the last hit.
+// A cursor from a listing with another order is rejected, as Elasticsearch
+// would not know what to do with its values.
+func (p PageRequest) searchAfter(order pageOrder) ([]interface{}, error) {
+	if p.Cursor == "" {
+		return nil, nil
+	}
+	invalid := ErrInvalidInput.WithDetails(map[string]interface{}{
+		"cursor": p.Cursor,
+	})
+	raw, err := base64.RawURLEncoding.DecodeString(p.Cursor)
+	if err != nil {
+		return nil, invalid
+	}
+	var c cursor
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&c); err != nil || c.Order != order.name || len(c.Values) != len(order.sorters) {
+		return nil, invalid
+	}
+	return c.Values, nil
+}
+
+func encodeCursor(order pageOrder, sortValues []interface{}) string {
+	if len(sortValues) == 0 {
+		return ""
+	}
+	raw, err := json.Marshal(cursor{Order: order.name, Values: sortValues})
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
@@ -0,0 +1,53 @@
+package articles
+
+import (
+	"context"
+	"time"
+
+	"github.com/afif-musyayyidin/hertz-boilerplate/domain/infra/logger"
+)
+
+// PurgeJob periodically hard-deletes articles that were soft-deleted longer
+// than the retention window ago. Their documents are already gone from the
+// index, so only Postgres is touched.
+type PurgeJob struct {
+	repo      ArticleRepository
+	interval  time.Duration
+	retention time.Duration
+}
+
+func NewPurgeJob(repo ArticleRepository, interval time.Duration, retention time.Duration) *PurgeJob {
+	return &PurgeJob{
+		repo:      repo,
+		interval:  interval,
+		retention: retention,
+	}
+}
+
+// Run blocks until ctx is cancelled.
+func (j *PurgeJob) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		j.purge(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j *PurgeJob) purge(ctx context.Context) {
+	purged, err := j.repo.PurgeDeleted(ctx, time.Now().Add(-j.retention))
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Debug("article purge failed", err)
+		}
+		return
+	}
+	if purged > 0 {
+		logger.Debug("purged soft-deleted articles", purged)
+	}
+}
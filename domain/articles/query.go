@@ -1,24 +1,187 @@
 package articles
 
 const CreateArticleQuery = `
-	INSERT INTO articles (id, title, body, author_id)
-	VALUES (:id, :title, :body, :author_id)
+	INSERT INTO articles (id, title, body, author_id, status, version)
+	VALUES (:id, :title, :body, :author_id, :status, :version)
 `
 
+// UpdateArticleQuery does not check the author: callers lock the row and
+// check who may edit it first.
 const UpdateArticleQuery = `
 	UPDATE articles
-	SET title = :title, body = :body, updated_at = :updated_at
-	WHERE id = :id
+	SET title = :title, body = :body, updated_at = :updated_at, version = version + 1
+	WHERE id = :id AND version = :version AND deleted_at IS NULL
 `
 
 const FindArticleByIDQuery = `
-	SELECT id, title, body, author_id FROM articles WHERE id = $1
+	SELECT id, title, body, author_id, status, publish_at, version, created_at, updated_at
+	FROM articles WHERE id = $1 AND deleted_at IS NULL
+`
+
+const FindArticleByIDForUpdateQuery = `
+	SELECT id, title, body, author_id, status, publish_at, version, created_at, updated_at, deleted_at
+	FROM articles WHERE id = $1
+	FOR UPDATE
 `
 
 const FindAllArticleByAuthorIDQuery = `
-	SELECT id, title, body, author_id FROM articles WHERE author_id = $1
+	SELECT id, title, body, author_id FROM articles WHERE author_id = $1 AND deleted_at IS NULL
 `
 
 const FindAllArticleWithAuthorByAuthorIDQuery = `
-	SELECT id, title, body, author_id FROM articles WHERE author_id = $1
+	SELECT id, title, body, author_id FROM articles WHERE author_id = $1 AND deleted_at IS NULL
+`
+
+const SoftDeleteArticleQuery = `
+	UPDATE articles
+	SET deleted_at = $2, updated_at = $2, version = version + 1
+	WHERE id = $1 AND deleted_at IS NULL
+`
+
+const RestoreArticleQuery = `
+	UPDATE articles
+	SET deleted_at = NULL, updated_at = $2, version = version + 1
+	WHERE id = $1 AND deleted_at IS NOT NULL
+`
+
+const UpdateArticleStatusQuery = `
+	UPDATE articles
+	SET status = $2,
+		publish_at = CASE WHEN $2 = 'draft' THEN NULL ELSE publish_at END,
+		updated_at = $3,
+		version = version + 1
+	WHERE id = $1 AND deleted_at IS NULL
+`
+
+const ScheduleArticleQuery = `
+	UPDATE articles
+	SET status = 'scheduled', publish_at = $2, updated_at = $3, version = version + 1
+	WHERE id = $1 AND deleted_at IS NULL
+`
+
+const FindDueScheduledArticleForUpdateQuery = `
+	SELECT id, title, body, author_id, status, publish_at, version, created_at, updated_at
+	FROM articles
+	WHERE status = 'scheduled' AND publish_at <= $1 AND deleted_at IS NULL
+	ORDER BY publish_at
+	LIMIT $2
+	FOR UPDATE SKIP LOCKED
+`
+
+const PurgeDeletedArticleQuery = `
+	DELETE FROM articles WHERE deleted_at IS NOT NULL AND deleted_at < $1
+`
+
+// CreateArticleRevisionQuery appends the next revision number for the
+// article. Callers hold the article row lock, so numbers cannot collide.
+const CreateArticleRevisionQuery = `
+	INSERT INTO article_revisions (id, article_id, revision, title, body, edited_by)
+	SELECT $1, $2, COALESCE(MAX(revision), 0) + 1, $3, $4, $5
+	FROM article_revisions WHERE article_id = $2
+`
+
+const FindArticleRevisionsQuery = `
+	SELECT id, article_id, revision, title, edited_by, created_at
+	FROM article_revisions WHERE article_id = $1
+	ORDER BY revision DESC
+`
+
+const FindArticleRevisionQuery = `
+	SELECT id, article_id, revision, title, body, edited_by, created_at
+	FROM article_revisions WHERE article_id = $1 AND revision = $2
+`
+
+const CreateOutboxEventQuery = `
+	INSERT INTO outbox (id, aggregate_id, event_type, payload)
+	VALUES ($1, $2, $3, $4::jsonb)
+`
+
+// ClaimPendingOutboxQuery only picks the oldest pending event of each
+// aggregate, so events for one article are relayed in the order they were
+// written even when several relays run. Claimed events are not due again for
+// $2 seconds, which keeps other relays off them without holding a lock.
+const ClaimPendingOutboxQuery = `
+	UPDATE outbox SET next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => $2)
+	WHERE id IN (
+		SELECT o.id
+		FROM outbox o
+		WHERE o.status = 'pending' AND o.next_attempt_at <= CURRENT_TIMESTAMP
+			AND NOT EXISTS (
+				SELECT 1 FROM outbox p
+				WHERE p.aggregate_id = o.aggregate_id AND p.status = 'pending' AND p.seq < o.seq
+			)
+		ORDER BY o.seq
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING id, seq, aggregate_id, event_type, payload, status, attempts, next_attempt_at, last_error, created_at
+`
+
+const DeleteOutboxEventQuery = `
+	DELETE FROM outbox WHERE id = $1
+`
+
+const UpdateOutboxEventFailureQuery = `
+	UPDATE outbox
+	SET status = $2, attempts = $3, next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => $4), last_error = $5
+	WHERE id = $1
+`
+
+const CountOutboxByStatusQuery = `
+	SELECT status, COUNT(*) AS count FROM outbox GROUP BY status
+`
+
+const FindArticlesForReindexQuery = `
+	SELECT a.id, a.title, a.body, a.author_id, a.status, a.publish_at, a.version, a.created_at, a.updated_at, a.deleted_at,
+		COALESCE(au.id, a.author_id) AS "author.id", COALESCE(au.name, '') AS "author.name",
+		COALESCE(au.slug, '') AS "author.slug", COALESCE(au.avatar_url, '') AS "author.avatar_url"
+	FROM articles a
+	LEFT JOIN authors au ON au.id = a.author_id
+	WHERE a.id > $1 AND a.deleted_at IS NULL
+	ORDER BY a.id
+	LIMIT $2
+`
+
+// FindArticlesChangedSinceForReindexQuery includes deleted rows so the
+// catch-up pass can remove them from the new index.
+const FindArticlesChangedSinceForReindexQuery = `
+	SELECT a.id, a.title, a.body, a.author_id, a.status, a.publish_at, a.version, a.created_at, a.updated_at, a.deleted_at,
+		COALESCE(au.id, a.author_id) AS "author.id", COALESCE(au.name, '') AS "author.name",
+		COALESCE(au.slug, '') AS "author.slug", COALESCE(au.avatar_url, '') AS "author.avatar_url"
+	FROM articles a
+	LEFT JOIN authors au ON au.id = a.author_id
+	WHERE a.id > $1 AND a.updated_at >= $2
+	ORDER BY a.id
+	LIMIT $3
+`
+
+const CountArticlesForReindexQuery = `
+	SELECT COUNT(*) FROM articles WHERE deleted_at IS NULL
+`
+
+const FindUnfinishedReindexCheckpointQuery = `
+	SELECT index_name, last_id, indexed, started_at, completed_at
+	FROM reindex_checkpoints
+	WHERE completed_at IS NULL
+	ORDER BY started_at DESC
+	LIMIT 1
+`
+
+const SaveReindexCheckpointQuery = `
+	INSERT INTO reindex_checkpoints (index_name, last_id, indexed, started_at, completed_at)
+	VALUES (:index_name, :last_id, :indexed, :started_at, :completed_at)
+	ON CONFLICT (index_name) DO UPDATE
+	SET last_id = EXCLUDED.last_id, indexed = EXCLUDED.indexed, completed_at = EXCLUDED.completed_at
+`
+
+const FindAuthorForIndexQuery = `
+	SELECT id, name, slug, avatar_url FROM authors WHERE id = $1
+`
+
+const FindArticlesByAuthorForIndexQuery = `
+	SELECT a.id, a.title, a.body, a.author_id, a.status, a.publish_at, a.version, a.created_at, a.updated_at, a.deleted_at,
+		au.id AS "author.id", au.name AS "author.name", au.slug AS "author.slug", au.avatar_url AS "author.avatar_url"
+	FROM articles a
+	JOIN authors au ON au.id = a.author_id
+	WHERE a.author_id = $1 AND a.deleted_at IS NULL
 `
@@ -0,0 +1,155 @@
+package articles
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// reindexClockSkew widens the catch-up window, so rows whose updated_at was
+// taken shortly before the window opened but committed after are not missed.
+const reindexClockSkew = time.Minute
+
+// ReindexCheckpoint records how far a reindex into IndexName got. Rows are
+// streamed in id order, so LastID is enough to resume.
+type ReindexCheckpoint struct {
+	IndexName   string     `db:"index_name"`
+	LastID      uuid.UUID  `db:"last_id"`
+	Indexed     int64      `db:"indexed"`
+	StartedAt   time.Time  `db:"started_at"`
+	CompletedAt *time.Time `db:"completed_at"`
+}
+
+// Reindexer rebuilds the articles index from Postgres into a new
+// articles_v{N} index and then points the articles alias at it. Searches keep
+// using the old index until the swap, and the outbox relay keeps writing to
+// it; a catch-up pass before and after the swap copies rows changed in the
+// meantime.
+type Reindexer struct {
+	repo      ArticleRepository
+	index     ArticleIndexer
+	batchSize int
+	out       io.Writer
+}
+
+func NewReindexer(repo ArticleRepository, index ArticleIndexer, batchSize int, out io.Writer) *Reindexer {
+	return &Reindexer{
+		repo:      repo,
+		index:     index,
+		batchSize: batchSize,
+		out:       out,
+	}
+}
+
+// Run reindexes every article. An interrupted run is resumed from its
+// checkpoint as long as its index still exists.
+func (r *Reindexer) Run(ctx context.Context) error {
+	checkpoint, err := r.checkpoint(ctx)
+	if err != nil {
+		return err
+	}
+	total, err := r.repo.CountForReindex(ctx)
+	if err != nil {
+		return err
+	}
+
+	for {
+		batch, err := r.repo.FindForReindex(ctx, checkpoint.LastID, r.batchSize)
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		if err := r.index.BulkIndex(ctx, checkpoint.IndexName, batch); err != nil {
+			return err
+		}
+		checkpoint.LastID = batch[len(batch)-1].ID
+		checkpoint.Indexed += int64(len(batch))
+		if err := r.repo.SaveReindexCheckpoint(ctx, checkpoint); err != nil {
+			return err
+		}
+		fmt.Fprintf(r.out, "indexed %d/%d articles\n", checkpoint.Indexed, total)
+	}
+
+	caughtUpAt := time.Now()
+	if err := r.catchUp(ctx, checkpoint.IndexName, checkpoint.StartedAt); err != nil {
+		return err
+	}
+	if err := r.index.SwapAlias(ctx, checkpoint.IndexName); err != nil {
+		return err
+	}
+	fmt.Fprintf(r.out, "alias %s now points at %s\n", ArticleIndexAlias, checkpoint.IndexName)
+	if err := r.catchUp(ctx, checkpoint.IndexName, caughtUpAt); err != nil {
+		return err
+	}
+
+	completedAt := time.Now()
+	checkpoint.CompletedAt = &completedAt
+	if err := r.repo.SaveReindexCheckpoint(ctx, checkpoint); err != nil {
+		return err
+	}
+	fmt.Fprintf(r.out, "reindex into %s done; previous indices are kept and can be deleted once verified\n", checkpoint.IndexName)
+	return nil
+}
+
+// checkpoint resumes the last unfinished run or starts a new one in a fresh
+// index.
+func (r *Reindexer) checkpoint(ctx context.Context) (*ReindexCheckpoint, error) {
+	checkpoint, err := r.repo.FindUnfinishedReindexCheckpoint(ctx)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if checkpoint != nil {
+		exists, err := r.index.IndexExists(ctx, checkpoint.IndexName)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			fmt.Fprintf(r.out, "resuming reindex into %s after %d articles\n", checkpoint.IndexName, checkpoint.Indexed)
+			return checkpoint, nil
+		}
+	}
+
+	name, err := r.index.CreateVersionedIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	checkpoint = &ReindexCheckpoint{
+		IndexName: name,
+		StartedAt: time.Now(),
+	}
+	if err := r.repo.SaveReindexCheckpoint(ctx, checkpoint); err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(r.out, "reindexing into %s\n", name)
+	return checkpoint, nil
+}
+
+// catchUp copies rows updated since the given time into index.
+func (r *Reindexer) catchUp(ctx context.Context, index string, since time.Time) error {
+	since = since.Add(-reindexClockSkew)
+	afterID := uuid.Nil
+	caughtUp := 0
+	for {
+		batch, err := r.repo.FindChangedSinceForReindex(ctx, since, afterID, r.batchSize)
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		if err := r.index.BulkIndex(ctx, index, batch); err != nil {
+			return err
+		}
+		afterID = batch[len(batch)-1].ID
+		caughtUp += len(batch)
+	}
+	fmt.Fprintf(r.out, "caught up %d articles changed since %s\n", caughtUp, since.Format(time.RFC3339))
+	return nil
+}
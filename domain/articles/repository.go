@@ -2,16 +2,39 @@ package articles
 
 import (
 	"context"
+	"time"
 
+	"github.com/afif-musyayyidin/hertz-boilerplate/domain/authors"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 )
 
 type ArticleRepository interface {
 	Save(ctx context.Context, u *ArticleInput, authorID uuid.UUID, tx *sqlx.Tx) (*Article, error)
-	Update(ctx context.Context, u *ArticleInput, id uuid.UUID, authorID uuid.UUID, tx *sqlx.Tx) (*uuid.UUID, error)
+	Update(ctx context.Context, u *ArticleInput, id uuid.UUID, editorID uuid.UUID, version int, tx *sqlx.Tx) (*uuid.UUID, error)
 	FindByID(ctx context.Context, id uuid.UUID) (*Article, error)
 	FindAllArticleByAuthorID(ctx context.Context, id uuid.UUID) ([]*Article, error)
 	FindAllArticleWithAuthorByAuthorID(ctx context.Context, id uuid.UUID) ([]*Article, error)
 	CreateManyArticle(ctx context.Context, u []*ArticleInput, authorID uuid.UUID, tx *sqlx.Tx) ([]Article, error)
+	FindByIDForUpdate(ctx context.Context, id uuid.UUID, tx *sqlx.Tx) (*Article, error)
+	Delete(ctx context.Context, id uuid.UUID, tx *sqlx.Tx) error
+	Restore(ctx context.Context, id uuid.UUID, tx *sqlx.Tx) error
+	UpdateStatus(ctx context.Context, id uuid.UUID, status Status, tx *sqlx.Tx) error
+	Schedule(ctx context.Context, id uuid.UUID, publishAt time.Time, tx *sqlx.Tx) error
+	FindDueScheduledForUpdate(ctx context.Context, now time.Time, limit int, tx *sqlx.Tx) ([]*Article, error)
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, error)
+	FindRevisions(ctx context.Context, articleID uuid.UUID) ([]*Revision, error)
+	FindRevision(ctx context.Context, articleID uuid.UUID, revision int) (*Revision, error)
+	EnqueueOutbox(ctx context.Context, eventType OutboxEventType, aggregateID uuid.UUID, payload interface{}, tx *sqlx.Tx) error
+	ClaimPendingOutbox(ctx context.Context, limit int, lease time.Duration) ([]*OutboxEvent, error)
+	DeleteOutboxEvent(ctx context.Context, id uuid.UUID) error
+	MarkOutboxEventFailed(ctx context.Context, event *OutboxEvent, retryIn time.Duration) error
+	CountOutboxByStatus(ctx context.Context) (map[string]int64, error)
+	FindForReindex(ctx context.Context, afterID uuid.UUID, limit int) ([]*Article, error)
+	FindChangedSinceForReindex(ctx context.Context, since time.Time, afterID uuid.UUID, limit int) ([]*Article, error)
+	CountForReindex(ctx context.Context) (int64, error)
+	FindUnfinishedReindexCheckpoint(ctx context.Context) (*ReindexCheckpoint, error)
+	SaveReindexCheckpoint(ctx context.Context, checkpoint *ReindexCheckpoint) error
+	FindAuthorForIndex(ctx context.Context, authorID uuid.UUID) (*authors.Author, error)
+	FindByAuthorForIndex(ctx context.Context, authorID uuid.UUID) ([]*Article, error)
 }
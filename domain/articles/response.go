@@ -0,0 +1,101 @@
+package articles
+
+import (
+	"time"
+
+	"github.com/afif-musyayyidin/hertz-boilerplate/domain/authors"
+	"github.com/google/uuid"
+)
+
+// ArticleResponse is an article as returned by the API. Handlers never
+// return domain structs directly, so fields added to Article or
+// authors.Author stay private until they are mapped here.
+type ArticleResponse struct {
+	ID         uuid.UUID             `json:"id"`
+	Title      string                `json:"title"`
+	Body       string                `json:"body,omitempty"`
+	AuthorID   uuid.UUID             `json:"author_id"`
+	Status     Status                `json:"status,omitempty"`
+	PublishAt  *time.Time            `json:"publish_at,omitempty"`
+	Version    int                   `json:"version,omitempty"`
+	CreatedAt  time.Time             `json:"created_at"`
+	UpdatedAt  time.Time             `json:"updated_at"`
+	Score      *float64              `json:"score,omitempty"`
+	Highlights map[string][]string   `json:"highlights,omitempty"`
+	Snippet    string                `json:"snippet,omitempty"`
+	Author     *authors.AuthorPublic `json:"author,omitempty"`
+}
+
+type ArticlePageResponse struct {
+	Articles   []*ArticleResponse `json:"articles"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+	Facets     *SearchFacets      `json:"facets,omitempty"`
+}
+
+// ArticleWithAuthorResponse is an author with a page of their articles.
+type ArticleWithAuthorResponse struct {
+	authors.AuthorPublic
+	Article    []*ArticleResponse `json:"article"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
+
+type ArticleWithAuthorPageResponse struct {
+	Authors    []*ArticleWithAuthorResponse `json:"authors"`
+	NextCursor string                       `json:"next_cursor,omitempty"`
+}
+
+func NewArticleResponse(a *Article) *ArticleResponse {
+	if a == nil {
+		return nil
+	}
+	return &ArticleResponse{
+		ID:         a.ID,
+		Title:      a.Title,
+		Body:       a.Body,
+		AuthorID:   a.AuthorID,
+		Status:     a.Status,
+		PublishAt:  a.PublishAt,
+		Version:    a.Version,
+		CreatedAt:  a.CreatedAt,
+		UpdatedAt:  a.UpdatedAt,
+		Score:      a.Score,
+		Highlights: a.Highlights,
+		Snippet:    a.Snippet,
+		Author:     authors.NewAuthorPublic(a.Author),
+	}
+}
+
+func NewArticleListResponse(list []*Article) []*ArticleResponse {
+	responses := make([]*ArticleResponse, 0, len(list))
+	for _, a := range list {
+		responses = append(responses, NewArticleResponse(a))
+	}
+	return responses
+}
+
+func NewArticlePageResponse(page *ArticlePage) *ArticlePageResponse {
+	return &ArticlePageResponse{
+		Articles:   NewArticleListResponse(page.Articles),
+		NextCursor: page.NextCursor,
+		Facets:     page.Facets,
+	}
+}
+
+func NewArticleWithAuthorResponse(a *ArticleWithAuthor) *ArticleWithAuthorResponse {
+	return &ArticleWithAuthorResponse{
+		AuthorPublic: *authors.NewAuthorPublic(&a.Author),
+		Article:      NewArticleListResponse(a.Article),
+		NextCursor:   a.NextCursor,
+	}
+}
+
+func NewArticleWithAuthorPageResponse(page *ArticleWithAuthorPage) *ArticleWithAuthorPageResponse {
+	responses := make([]*ArticleWithAuthorResponse, 0, len(page.Authors))
+	for _, a := range page.Authors {
+		responses = append(responses, NewArticleWithAuthorResponse(a))
+	}
+	return &ArticleWithAuthorPageResponse{
+		Authors:    responses,
+		NextCursor: page.NextCursor,
+	}
+}
@@ -0,0 +1,30 @@
+package articles
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Revision is a snapshot of an article's content, taken every time the
+// content is saved. Revision numbers start at 1 for each article.
+type Revision struct {
+	ID        uuid.UUID `db:"id" json:"id"`
+	ArticleID uuid.UUID `db:"article_id" json:"article_id"`
+	Revision  int       `db:"revision" json:"revision"`
+	Title     string    `db:"title" json:"title"`
+	Body      string    `db:"body" json:"body,omitempty"`
+	EditedBy  uuid.UUID `db:"edited_by" json:"edited_by"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// RevisionDiff compares a revision with the current version of the article.
+type RevisionDiff struct {
+	Revision  *Revision  `json:"revision"`
+	TitleDiff []DiffLine `json:"title_diff"`
+	BodyDiff  []DiffLine `json:"body_diff"`
+}
+
+func (r *Revision) TableName() string {
+	return "article_revisions"
+}
@@ -0,0 +1,93 @@
+package articles
+
+import (
+	"context"
+	"time"
+
+	"github.com/afif-musyayyidin/hertz-boilerplate/domain/infra/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+// PublishScheduler publishes scheduled articles once their publish_at has
+// passed. Due rows are claimed with FOR UPDATE SKIP LOCKED, so several
+// replicas can run it at the same time without publishing an article twice.
+type PublishScheduler struct {
+	repo      ArticleRepository
+	db        *sqlx.DB
+	interval  time.Duration
+	batchSize int
+}
+
+func NewPublishScheduler(repo ArticleRepository, db *sqlx.DB, interval time.Duration, batchSize int) *PublishScheduler {
+	return &PublishScheduler{
+		repo:      repo,
+		db:        db,
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+// Run blocks until ctx is cancelled. A batch that is in flight when ctx is
+// cancelled is rolled back and picked up again on the next start.
+func (s *PublishScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.publishAllDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *PublishScheduler) publishAllDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		published, err := s.PublishDue(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Debug("scheduled publish failed", err)
+			}
+			return
+		}
+		if published > 0 {
+			logger.Debug("published scheduled articles", published)
+		}
+		if published < s.batchSize {
+			return
+		}
+	}
+}
+
+// PublishDue publishes one batch of due articles in a single transaction and
+// returns how many were published.
+func (s *PublishScheduler) PublishDue(ctx context.Context) (int, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	due, err := s.repo.FindDueScheduledForUpdate(ctx, time.Now(), s.batchSize, tx)
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, err
+	}
+	for _, article := range due {
+		if err := s.repo.UpdateStatus(ctx, article.ID, StatusPublished, tx); err != nil {
+			_ = tx.Rollback()
+			return 0, err
+		}
+		article.Status = StatusPublished
+		article.Version++
+		article.UpdatedAt = time.Now()
+		if err := s.repo.EnqueueOutbox(ctx, EventArticleUpsert, article.ID, article, tx); err != nil {
+			_ = tx.Rollback()
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(due), nil
+}
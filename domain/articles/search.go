@@ -0,0 +1,274 @@
+package articles
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olivere/elastic/v7"
+)
+
+const (
+	DefaultFragmentSize = 150
+	MinFragmentSize     = 20
+	MaxFragmentSize     = 1000
+	DefaultPreTag       = "<em>"
+	DefaultPostTag      = "</em>"
+
+	DateFieldCreatedAt = "created_at"
+	DateFieldUpdatedAt = "updated_at"
+
+	DefaultRelatedSize = 5
+	MaxRelatedSize     = 20
+
+	// maxAuthorFacets caps the author buckets; the authors with the most
+	// matching articles are kept.
+	maxAuthorFacets = 20
+)
+
+// highlightTags are the elements allowed to mark a highlighted term. The tags
+// come from the query string and end up unescaped in the response, so
+// nothing else is accepted.
+var highlightTags = map[string]bool{
+	"em":     true,
+	"strong": true,
+	"mark":   true,
+	"b":      true,
+	"i":      true,
+}
+
+// SearchRequest is an article search. Keyword is matched for relevance and
+// may be empty, in which case every article passing the filters matches,
+// newest first. Every filter that is set must match:
+//   - AuthorIDs and Statuses match any of the listed values,
+//   - From (inclusive) and To (exclusive) bound DateField, created_at unless
+//     set to updated_at.
+//
+// Fields limits the article fields that are returned, empty means all of
+// them; id, author_id and author are always returned.
+type SearchRequest struct {
+	Keyword   string
+	AuthorIDs []uuid.UUID
+	Statuses  []Status
+	DateField string
+	From      *time.Time
+	To        *time.Time
+	Fields    []string
+	Highlight HighlightOptions
+}
+
+// HighlightOptions control the highlighted fragments. Zero values fall back
+// to the defaults above, each on its own. PreTag and PostTag must open and
+// close one of highlightTags, e.g. <mark> and </mark>.
+type HighlightOptions struct {
+	FragmentSize int
+	PreTag       string
+	PostTag      string
+}
+
+// SearchFacets count the articles matching the whole search, not only the
+// returned page. Buckets are ordered by count, months by date.
+type SearchFacets struct {
+	Authors  []FacetBucket `json:"authors"`
+	Months   []FacetBucket `json:"months"`
+	Statuses []FacetBucket `json:"statuses"`
+}
+
+// FacetBucket is one value of a facet. Key is the value to filter on: the
+// author ID, the month as yyyy-MM or the status. Name is the author name.
+type FacetBucket struct {
+	Key   string `json:"key"`
+	Name  string `json:"name,omitempty"`
+	Count int64  `json:"count"`
+}
+
+// sourceFields are the article fields that can be selected with
+// SearchRequest.Fields.
+var sourceFields = map[string]bool{
+	"id":         true,
+	"title":      true,
+	"body":       true,
+	"author_id":  true,
+	"status":     true,
+	"publish_at": true,
+	"version":    true,
+	"created_at": true,
+	"updated_at": true,
+}
+
+// includes returns the _source fields to fetch, or nil for all of them.
+func (r SearchRequest) includes() ([]string, error) {
+	if len(r.Fields) == 0 {
+		return nil, nil
+	}
+	includes := []string{"id", "author_id", "author"}
+	for _, field := range r.Fields {
+		field = strings.TrimSpace(field)
+		if !sourceFields[field] {
+			return nil, ErrInvalidInput.WithDetails(map[string]interface{}{
+				"fields": r.Fields,
+			})
+		}
+		includes = append(includes, field)
+	}
+	return includes, nil
+}
+
+func (r SearchRequest) dateField() string {
+	if r.DateField == "" {
+		return DateFieldCreatedAt
+	}
+	return r.DateField
+}
+
+// filters validates the filters of the request and turns them into queries.
+func (r SearchRequest) filters() ([]elastic.Query, error) {
+	var filters []elastic.Query
+	if len(r.AuthorIDs) > 0 {
+		ids := make([]interface{}, len(r.AuthorIDs))
+		for n, id := range r.AuthorIDs {
+			ids[n] = id.String()
+		}
+		filters = append(filters, elastic.NewTermsQuery("author_id", ids...))
+	}
+	if len(r.Statuses) > 0 {
+		statuses := make([]interface{}, len(r.Statuses))
+		for n, status := range r.Statuses {
+			if !status.valid() {
+				return nil, ErrInvalidInput.WithDetails(map[string]interface{}{
+					"status": status,
+				})
+			}
+			statuses[n] = string(status)
+		}
+		filters = append(filters, elastic.NewTermsQuery("status", statuses...))
+	}
+	field := r.dateField()
+	if field != DateFieldCreatedAt && field != DateFieldUpdatedAt {
+		return nil, ErrInvalidInput.WithDetails(map[string]interface{}{
+			"date_field": r.DateField,
+		})
+	}
+	if r.From != nil && r.To != nil && !r.From.Before(*r.To) {
+		return nil, ErrInvalidInput.WithDetails(map[string]interface{}{
+			"from": r.From,
+			"to":   r.To,
+		})
+	}
+	if r.From != nil || r.To != nil {
+		dateRange := elastic.NewRangeQuery(field)
+		if r.From != nil {
+			dateRange = dateRange.Gte(r.From.UTC().Format(time.RFC3339Nano))
+		}
+		if r.To != nil {
+			dateRange = dateRange.Lt(r.To.UTC().Format(time.RFC3339Nano))
+		}
+		filters = append(filters, dateRange)
+	}
+	return filters, nil
+}
+
+// aggregations returns the facet aggregations by name.
+func (r SearchRequest) aggregations() map[string]elastic.Aggregation {
+	return map[string]elastic.Aggregation{
+		"authors": elastic.NewTermsAggregation().
+			Field("author_id").
+			Size(maxAuthorFacets).
+			SubAggregation("name", elastic.NewTermsAggregation().Field("author.name.keyword").Size(1)),
+		"months": elastic.NewDateHistogramAggregation().
+			Field(r.dateField()).
+			CalendarInterval("month").
+			Format("yyyy-MM").
+			MinDocCount(1),
+		"statuses": elastic.NewTermsAggregation().
+			Field("status"),
+	}
+}
+
+func parseFacets(aggs elastic.Aggregations) *SearchFacets {
+	facets := &SearchFacets{
+		Authors:  []FacetBucket{},
+		Months:   []FacetBucket{},
+		Statuses: []FacetBucket{},
+	}
+	if terms, ok := aggs.Terms("authors"); ok {
+		facets.Authors = termBuckets(terms)
+		for n, bucket := range terms.Buckets {
+			if names, ok := bucket.Terms("name"); ok && len(names.Buckets) > 0 {
+				facets.Authors[n].Name = fmt.Sprint(names.Buckets[0].Key)
+			}
+		}
+	}
+	if terms, ok := aggs.Terms("statuses"); ok {
+		facets.Statuses = termBuckets(terms)
+	}
+	if histogram, ok := aggs.DateHistogram("months"); ok {
+		for _, bucket := range histogram.Buckets {
+			if bucket.KeyAsString == nil {
+				continue
+			}
+			facets.Months = append(facets.Months, FacetBucket{
+				Key:   *bucket.KeyAsString,
+				Count: bucket.DocCount,
+			})
+		}
+	}
+	return facets
+}
+
+func termBuckets(terms *elastic.AggregationBucketKeyItems) []FacetBucket {
+	buckets := make([]FacetBucket, 0, len(terms.Buckets))
+	for _, bucket := range terms.Buckets {
+		buckets = append(buckets, FacetBucket{
+			Key:   fmt.Sprint(bucket.Key),
+			Count: bucket.DocCount,
+		})
+	}
+	return buckets
+}
+
+func relatedSize(size int) int {
+	if size <= 0 {
+		return DefaultRelatedSize
+	}
+	if size > MaxRelatedSize {
+		return MaxRelatedSize
+	}
+	return size
+}
+
+func (h HighlightOptions) fragmentSize() int {
+	switch {
+	case h.FragmentSize <= 0:
+		return DefaultFragmentSize
+	case h.FragmentSize < MinFragmentSize:
+		return MinFragmentSize
+	case h.FragmentSize > MaxFragmentSize:
+		return MaxFragmentSize
+	}
+	return h.FragmentSize
+}
+
+func (h HighlightOptions) tags() (string, string, error) {
+	preTag, postTag := h.PreTag, h.PostTag
+	if preTag == "" {
+		preTag = DefaultPreTag
+	}
+	if postTag == "" {
+		postTag = DefaultPostTag
+	}
+	name := strings.TrimSuffix(strings.TrimPrefix(preTag, "<"), ">")
+	if !highlightTags[name] || preTag != "<"+name+">" {
+		return "", "", ErrInvalidInput.WithDetails(map[string]interface{}{
+			"pre_tag": h.PreTag,
+		})
+	}
+	name = strings.TrimSuffix(strings.TrimPrefix(postTag, "</"), ">")
+	if !highlightTags[name] || postTag != "</"+name+">" {
+		return "", "", ErrInvalidInput.WithDetails(map[string]interface{}{
+			"post_tag": h.PostTag,
+		})
+	}
+	return preTag, postTag, nil
+}
@@ -0,0 +1,35 @@
+package articles
+
+type Status string
+
+const (
+	StatusDraft     Status = "draft"
+	StatusInReview  Status = "in_review"
+	StatusScheduled Status = "scheduled"
+	StatusPublished Status = "published"
+	StatusArchived  Status = "archived"
+)
+
+// statusTransitions lists, for every status, the statuses an article may move
+// to next.
+var statusTransitions = map[Status][]Status{
+	StatusDraft:     {StatusInReview, StatusScheduled, StatusPublished, StatusArchived},
+	StatusInReview:  {StatusDraft, StatusScheduled, StatusPublished, StatusArchived},
+	StatusScheduled: {StatusDraft, StatusPublished, StatusArchived},
+	StatusPublished: {StatusDraft, StatusArchived},
+	StatusArchived:  {},
+}
+
+func (s Status) CanTransitionTo(next Status) bool {
+	for _, allowed := range statusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+func (s Status) valid() bool {
+	_, ok := statusTransitions[s]
+	return ok
+}
@@ -0,0 +1,66 @@
+package articles
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MinSuggestLength is the number of characters typed before anything
+	// is suggested.
+	MinSuggestLength   = 2
+	DefaultSuggestSize = 8
+	MaxSuggestSize     = 20
+
+	// SuggestTimeout is the latency budget of a suggest request. Sources
+	// that do not answer in time are left out of the result.
+	SuggestTimeout = 150 * time.Millisecond
+)
+
+type SuggestionType string
+
+const (
+	SuggestionArticle SuggestionType = "article"
+	SuggestionAuthor  SuggestionType = "author"
+)
+
+// Suggestion is an article title or an author name matching what has been
+// typed so far. Score is between 0 and 1; article and author scores are
+// each normalized so the two sources can be ranked together.
+type Suggestion struct {
+	Type  SuggestionType `json:"type"`
+	ID    uuid.UUID      `json:"id"`
+	Text  string         `json:"text"`
+	Score float64        `json:"score"`
+}
+
+func suggestSize(size int) int {
+	if size <= 0 {
+		return DefaultSuggestSize
+	}
+	if size > MaxSuggestSize {
+		return MaxSuggestSize
+	}
+	return size
+}
+
+// mergeSuggestions ranks suggestions from all sources by score, then
+// alphabetically, and keeps the best size of them.
+func mergeSuggestions(size int, sources ...[]*Suggestion) []*Suggestion {
+	merged := []*Suggestion{}
+	for _, suggestions := range sources {
+		merged = append(merged, suggestions...)
+	}
+	sort.SliceStable(merged, func(a, b int) bool {
+		if merged[a].Score != merged[b].Score {
+			return merged[a].Score > merged[b].Score
+		}
+		return merged[a].Text < merged[b].Text
+	})
+	if len(merged) > size {
+		merged = merged[:size]
+	}
+	return merged
+}
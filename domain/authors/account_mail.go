@@ -0,0 +1,68 @@
+package authors
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/afif-musyayyidin/hertz-boilerplate/domain/infra"
+)
+
+// AccountMail writes the emails of the account flows and sends them through
+// a Mailer.
+type AccountMail struct {
+	mailer           infra.Mailer
+	passwordResetURL string
+	verifyEmailURL   string
+}
+
+// NewAccountMail sends links pointing at passwordResetURL and
+// verifyEmailURL, which get the token appended as the token query parameter.
+func NewAccountMail(mailer infra.Mailer, passwordResetURL string, verifyEmailURL string) *AccountMail {
+	return &AccountMail{mailer: mailer, passwordResetURL: passwordResetURL, verifyEmailURL: verifyEmailURL}
+}
+
+func (a *AccountMail) SendPasswordReset(ctx context.Context, author *Author, token string) error {
+	link, err := withToken(a.passwordResetURL, token)
+	if err != nil {
+		return err
+	}
+	return a.mailer.Send(ctx, infra.Mail{
+		To:      author.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"Someone asked to reset the password of your account. Open this link to choose a new one:\n\n"+
+			"%s\n\n"+
+			"The link works once and expires in %d minutes. If you did not ask for it, you can ignore this email.\n",
+			author.Name, link, int(PasswordResetTTL.Minutes())),
+	})
+}
+
+// SendEmailVerification sends the verification link to email, which is the
+// address of author or the one they are changing to.
+func (a *AccountMail) SendEmailVerification(ctx context.Context, author *Author, email string, token string) error {
+	link, err := withToken(a.verifyEmailURL, token)
+	if err != nil {
+		return err
+	}
+	return a.mailer.Send(ctx, infra.Mail{
+		To:      email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"Open this link to confirm that %s is your email address:\n\n"+
+			"%s\n\n"+
+			"The link expires in %d hours. If you did not sign up or change your email, you can ignore this email.\n",
+			author.Name, email, link, int(EmailVerificationTTL.Hours())),
+	})
+}
+
+func withToken(base string, token string) (string, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
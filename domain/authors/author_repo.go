@@ -2,7 +2,13 @@ package authors
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/afif-musyayyidin/hertz-boilerplate/domain/infra"
 	"github.com/afif-musyayyidin/hertz-boilerplate/domain/infra/logger"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
@@ -17,25 +23,253 @@ func NewAuthorRepo(db *sqlx.DB, dbReplica *sqlx.DB) AuthorRepository {
 	return &AuthorRepo{db: db, dbReplica: dbReplica}
 }
 
-func (r *AuthorRepo) Save(ctx context.Context, u *AuthorInput) (*uuid.UUID, error) {
-	newAuthor := CreateNewAuthor(*u)
-	_, err := r.db.NamedExecContext(ctx, CreateAuthorQuery, newAuthor)
+// Save inserts the author with a slug made from the name. A slug that is
+// taken gets the first free number appended: budi, budi-2, budi-3...
+// maxSlugAttempts bounds how often Save picks a new slug after a concurrent
+// create took the one it chose.
+const maxSlugAttempts = 5
+
+func (r *AuthorRepo) Save(ctx context.Context, u *AuthorInput, tx *sqlx.Tx) (*uuid.UUID, error) {
+	newAuthor, err := CreateNewAuthor(*u)
 	if err != nil {
 		return nil, err
 	}
+	base := newAuthor.Slug
+	for attempt := 1; ; attempt++ {
+		var taken []string
+		if err := tx.SelectContext(ctx, &taken, FindSlugsQuery, base); err != nil {
+			return nil, err
+		}
+		newAuthor.Slug = freeSlug(base, taken)
+		if _, err := tx.ExecContext(ctx, SavepointCreateAuthorQuery); err != nil {
+			return nil, err
+		}
+		_, err := tx.NamedExecContext(ctx, CreateAuthorQuery, newAuthor)
+		if err == nil {
+			break
+		}
+		if !infra.IsUniqueViolation(err, "uq_authors_slug") || attempt == maxSlugAttempts {
+			return nil, err
+		}
+		// the other create has committed by now, so the next FindSlugsQuery
+		// sees its slug
+		if _, err := tx.ExecContext(ctx, RollbackToSavepointCreateAuthorQuery); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, ReleaseSavepointCreateAuthorQuery); err != nil {
+		return nil, err
+	}
 
 	return &newAuthor.ID, nil
 }
 
-func (r *AuthorRepo) Update(ctx context.Context, u *AuthorInput, id uuid.UUID) (*uuid.UUID, error) {
+func (r *AuthorRepo) Update(ctx context.Context, u *AuthorInput, id uuid.UUID, tx *sqlx.Tx) (*uuid.UUID, error) {
 	author := u.ToAuthorUpdate(id)
-	_, err := r.db.NamedExecContext(ctx, UpdateAuthorQuery, author)
+	_, err := tx.NamedExecContext(ctx, UpdateAuthorQuery, author)
 	if err != nil {
 		return nil, err
 	}
 	return &id, nil
 }
 
+func (r *AuthorRepo) UpdateRole(ctx context.Context, id uuid.UUID, role Role) error {
+	result, err := r.db.ExecContext(ctx, UpdateAuthorRoleQuery, id, role)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// EnqueueSync records, inside tx, that the articles of the author have to be
+// indexed again with the new author data.
+func (r *AuthorRepo) EnqueueSync(ctx context.Context, id uuid.UUID, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, EnqueueAuthorSyncQuery, uuid.New(), id, EventAuthorSync)
+	return err
+}
+
+func (r *AuthorRepo) SaveRefreshToken(ctx context.Context, t *RefreshToken, tx *sqlx.Tx) error {
+	_, err := tx.NamedExecContext(ctx, CreateRefreshTokenQuery, t)
+	return err
+}
+
+// FindRefreshTokenForUpdate locks the refresh token with the given hash, so
+// two refreshes with the same token are handled one after the other.
+func (r *AuthorRepo) FindRefreshTokenForUpdate(ctx context.Context, hash string, tx *sqlx.Tx) (*RefreshToken, error) {
+	var t RefreshToken
+	if err := tx.GetContext(ctx, &t, FindRefreshTokenForUpdateQuery, hash); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *AuthorRepo) MarkRefreshTokenUsed(ctx context.Context, id uuid.UUID, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, MarkRefreshTokenUsedQuery, id)
+	return err
+}
+
+func (r *AuthorRepo) RevokeTokenFamily(ctx context.Context, familyID uuid.UUID, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, RevokeTokenFamilyQuery, familyID)
+	return err
+}
+
+func (r *AuthorRepo) FindTokenFamily(ctx context.Context, authorID uuid.UUID, accessTokenID uuid.UUID, tx *sqlx.Tx) (uuid.UUID, error) {
+	var familyID uuid.UUID
+	if err := tx.GetContext(ctx, &familyID, FindTokenFamilyQuery, authorID, accessTokenID); err != nil {
+		return uuid.Nil, err
+	}
+	return familyID, nil
+}
+
+// RevokeAccessToken puts an access token on the denylist and drops the
+// entries that have expired in the meantime.
+func (r *AuthorRepo) RevokeAccessToken(ctx context.Context, id uuid.UUID, expiresAt time.Time, tx *sqlx.Tx) error {
+	if _, err := tx.ExecContext(ctx, RevokeAccessTokenQuery, id, expiresAt); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, DeleteExpiredRevokedTokensQuery)
+	return err
+}
+
+// IsTokenRevoked reads the primary, so a logout is effective immediately.
+func (r *AuthorRepo) IsTokenRevoked(ctx context.Context, id uuid.UUID) (bool, error) {
+	var revoked bool
+	if err := r.db.GetContext(ctx, &revoked, IsTokenRevokedQuery, id); err != nil {
+		return false, err
+	}
+	return revoked, nil
+}
+
+func (r *AuthorRepo) SaveLoginAudit(ctx context.Context, audit *LoginAudit) error {
+	_, err := r.db.NamedExecContext(ctx, CreateLoginAuditQuery, audit)
+	return err
+}
+
+// FindPassword returns the password hash of author id. It reads the
+// primary, so a password changed a moment ago is seen.
+func (r *AuthorRepo) FindPassword(ctx context.Context, id uuid.UUID) (string, error) {
+	var hash string
+	if err := r.db.GetContext(ctx, &hash, FindAuthorPasswordQuery, id); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+// FindRole returns the role of author id. It reads the primary, so a role
+// changed a moment ago is seen.
+func (r *AuthorRepo) FindRole(ctx context.Context, id uuid.UUID) (Role, error) {
+	var role Role
+	if err := r.db.GetContext(ctx, &role, FindAuthorRoleQuery, id); err != nil {
+		return "", err
+	}
+	return role, nil
+}
+
+func (r *AuthorRepo) UpdatePassword(ctx context.Context, id uuid.UUID, hash string, tx *sqlx.Tx) error {
+	result, err := tx.ExecContext(ctx, UpdateAuthorPasswordQuery, id, hash)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *AuthorRepo) RevokeAuthorTokens(ctx context.Context, authorID uuid.UUID, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, RevokeAuthorTokensQuery, authorID)
+	return err
+}
+
+func (r *AuthorRepo) SavePasswordResetToken(ctx context.Context, t *PasswordResetToken, tx *sqlx.Tx) error {
+	_, err := tx.NamedExecContext(ctx, CreatePasswordResetTokenQuery, t)
+	return err
+}
+
+// FindPasswordResetTokenForUpdate locks the reset token with the given hash,
+// so it cannot be used twice by concurrent requests.
+func (r *AuthorRepo) FindPasswordResetTokenForUpdate(ctx context.Context, hash string, tx *sqlx.Tx) (*PasswordResetToken, error) {
+	var t PasswordResetToken
+	if err := tx.GetContext(ctx, &t, FindPasswordResetTokenForUpdateQuery, hash); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *AuthorRepo) HasRecentPasswordReset(ctx context.Context, authorID uuid.UUID, since time.Time, tx *sqlx.Tx) (bool, error) {
+	var recent bool
+	err := tx.GetContext(ctx, &recent, HasRecentPasswordResetQuery, authorID, since)
+	return recent, err
+}
+
+func (r *AuthorRepo) UsePasswordResetTokens(ctx context.Context, authorID uuid.UUID, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, UsePasswordResetTokensQuery, authorID)
+	return err
+}
+
+// FindForUpdate locks author id inside tx and loads the fields of the email
+// verification.
+func (r *AuthorRepo) FindForUpdate(ctx context.Context, id uuid.UUID, tx *sqlx.Tx) (*Author, error) {
+	var u Author
+	if err := tx.GetContext(ctx, &u, FindAuthorForUpdateQuery, id); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// EmailInUse tells whether an author other than exceptID has email as their
+// email or is waiting to verify it.
+func (r *AuthorRepo) EmailInUse(ctx context.Context, email string, exceptID uuid.UUID, tx *sqlx.Tx) (bool, error) {
+	var inUse bool
+	err := tx.GetContext(ctx, &inUse, EmailInUseQuery, email, exceptID)
+	return inUse, err
+}
+
+// SetPendingEmail records the address author id is changing to; nil cancels
+// the change.
+func (r *AuthorRepo) SetPendingEmail(ctx context.Context, id uuid.UUID, email *string, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, SetPendingEmailQuery, id, email)
+	return err
+}
+
+func (r *AuthorRepo) MarkVerificationSent(ctx context.Context, id uuid.UUID, at time.Time, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, MarkVerificationSentQuery, id, at)
+	return err
+}
+
+// VerifyEmail marks email of author id verified, making it the login email
+// when it was pending. ErrEmailTaken means another author got the address
+// first.
+func (r *AuthorRepo) VerifyEmail(ctx context.Context, id uuid.UUID, email string, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, VerifyEmailQuery, id, email)
+	if infra.IsUniqueViolation(err, "uq_authors_email") {
+		return ErrEmailTaken.WithDetails(map[string]interface{}{
+			"email": email,
+		})
+	}
+	return err
+}
+
+func (r *AuthorRepo) FindBySlug(ctx context.Context, slug string) (*Author, error) {
+	var u Author
+	if err := r.dbReplica.GetContext(ctx, &u, FindAuthorBySlugQuery, slug); err != nil {
+		logger.Debug("error find by slug", err)
+		return nil, err
+	}
+	return &u, nil
+}
+
 func (r *AuthorRepo) FindByID(ctx context.Context, id uuid.UUID) (*Author, error) {
 	var u Author
 	if err := r.dbReplica.GetContext(ctx, &u, FindAuthorByIDQuery, id); err != nil {
@@ -60,20 +294,97 @@ func (r *AuthorRepo) FindByIDList(ctx context.Context, idList []uuid.UUID) ([]Au
 	return uList, nil
 }
 
+// maxAuthorsByName bounds the authors FindIDNameByName returns, so a short
+// name matching most authors does not turn into a huge Elasticsearch query.
+const maxAuthorsByName = 500
+
 func (r *AuthorRepo) FindIDNameByName(ctx context.Context, name string) ([]*AuthorIDName, error) {
 	var idNameList []*AuthorIDName
-	if err := r.dbReplica.SelectContext(ctx, &idNameList, GetIDAuthorsByNameQuery, name); err != nil {
+	if err := r.dbReplica.SelectContext(ctx, &idNameList, GetIDAuthorsByNameQuery, escapeLike(name), maxAuthorsByName); err != nil {
 		logger.Debug("error find by name", err)
 		return nil, err
 	}
 	return idNameList, nil
 }
 
+// FindByEmail loads the login credentials of the author with email. It reads
+// the primary, so a password changed a moment ago is seen.
 func (r *AuthorRepo) FindByEmail(ctx context.Context, email string) (*Author, error) {
 	var u Author
-	if err := r.dbReplica.GetContext(ctx, &u, FindAuthorByEmailQuery, email); err != nil {
+	if err := r.db.GetContext(ctx, &u, FindAuthorByEmailQuery, email); err != nil {
 		logger.Debug("error find by email", err)
 		return nil, err
 	}
 	return &u, nil
-}
\ No newline at end of file
+}
+
+func (r *AuthorRepo) SuggestByName(ctx context.Context, prefix string, limit int) ([]*AuthorSuggestion, error) {
+	suggestions := []*AuthorSuggestion{}
+	if err := r.dbReplica.SelectContext(ctx, &suggestions, SuggestAuthorsByNameQuery, prefix, escapeLike(prefix), limit); err != nil {
+		logger.Debug("error suggest by name", err)
+		return nil, err
+	}
+	return suggestions, nil
+}
+
+// authorSortClauses fill in FindAuthorsPageQuery for every AuthorSort.
+var authorSortClauses = map[AuthorSort][]interface{}{
+	SortByName:          {"name", ">", "ASC"},
+	SortByNameDesc:      {"name", "<", "DESC"},
+	SortByCreatedAt:     {"created_at", ">", "ASC"},
+	SortByCreatedAtDesc: {"created_at", "<", "DESC"},
+}
+
+// FindPage reads one page of authors from the replica. One extra row is
+// fetched to know whether a next page exists.
+func (r *AuthorRepo) FindPage(ctx context.Context, page PageRequest) (*AuthorPage, error) {
+	clauses, ok := authorSortClauses[page.sort()]
+	if !ok {
+		return nil, ErrInvalidInput.WithDetails(map[string]interface{}{
+			"sort": page.Sort,
+		})
+	}
+	after, err := page.after()
+	if err != nil {
+		return nil, err
+	}
+	first := after == nil
+	if first {
+		after = &authorCursor{}
+	}
+	var position interface{} = after.Name
+	if clauses[0] == "created_at" {
+		position = after.CreatedAt
+	}
+
+	size := page.size()
+	authors := []*Author{}
+	query := fmt.Sprintf(FindAuthorsPageQuery, clauses...)
+	if err := r.dbReplica.SelectContext(ctx, &authors, query, escapeLike(page.Name), first, position, after.ID, size+1); err != nil {
+		logger.Debug("error find page", err)
+		return nil, err
+	}
+	result := &AuthorPage{Authors: authors}
+	if len(authors) > size {
+		result.Authors = authors[:size]
+		result.NextCursor = encodeCursor(result.Authors[size-1])
+	}
+	return result, nil
+}
+
+func freeSlug(base string, taken []string) string {
+	used := make(map[string]bool, len(taken))
+	for _, slug := range taken {
+		used[slug] = true
+	}
+	slug := base
+	for n := 2; used[slug]; n++ {
+		slug = base + "-" + strconv.Itoa(n)
+	}
+	return slug
+}
+
+// escapeLike escapes the LIKE wildcards in s, so it is matched literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
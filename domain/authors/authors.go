@@ -7,13 +7,45 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Author is an author account. Name is the display name shown with their
+// articles; LegalName is kept private.
 type Author struct {
-	ID        uuid.UUID `db:"id" json:"id"`
-	Name      string    `db:"name" json:"name"`
-	Email     string    `db:"email" json:"email"`
-	Password  string    `db:"password" json:"password"`
-	CreatedAt time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+	ID          uuid.UUID   `db:"id" json:"id"`
+	Name        string      `db:"name" json:"name"`
+	LegalName   string      `db:"legal_name" json:"legal_name,omitempty"`
+	Slug        string      `db:"slug" json:"slug,omitempty"`
+	Bio         string      `db:"bio" json:"bio,omitempty"`
+	AvatarURL   string      `db:"avatar_url" json:"avatar_url,omitempty"`
+	Website     string      `db:"website" json:"website,omitempty"`
+	SocialLinks SocialLinks `db:"social_links" json:"social_links,omitempty"`
+	Email       string      `db:"email" json:"email"`
+	Password    string      `db:"password" json:"-"`
+	Role        Role        `db:"role" json:"role,omitempty"`
+	CreatedAt   time.Time   `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time   `db:"updated_at" json:"updated_at"`
+
+	// EmailVerifiedAt is nil until Email is verified. PendingEmail is the
+	// address the author is changing to, which replaces Email once verified.
+	EmailVerifiedAt    *time.Time `db:"email_verified_at" json:"email_verified_at,omitempty"`
+	PendingEmail       *string    `db:"pending_email" json:"pending_email,omitempty"`
+	VerificationSentAt *time.Time `db:"verification_sent_at" json:"-"`
+}
+
+func (u *Author) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// AuthorPublic is the part of an author that may be shown to anyone. API
+// responses only ever contain authors in this form.
+type AuthorPublic struct {
+	ID          uuid.UUID   `json:"id"`
+	Name        string      `json:"name"`
+	Slug        string      `json:"slug,omitempty"`
+	Bio         string      `json:"bio,omitempty"`
+	AvatarURL   string      `json:"avatar_url,omitempty"`
+	Website     string      `json:"website,omitempty"`
+	SocialLinks SocialLinks `json:"social_links,omitempty"`
+	CreatedAt   *time.Time  `json:"created_at,omitempty"`
 }
 
 type AuthorIDName struct {
@@ -21,10 +53,28 @@ type AuthorIDName struct {
 	Name string    `db:"name" json:"name"`
 }
 
+// AuthorSuggestion is an author proposed while typing a name. Score is
+// between 0 and 1.
+type AuthorSuggestion struct {
+	ID    uuid.UUID `db:"id" json:"id"`
+	Name  string    `db:"name" json:"name"`
+	Score float64   `db:"score" json:"score"`
+}
+
 type AuthorInput struct {
-	Name     string `json:"name"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Name        string      `json:"name"`
+	LegalName   string      `json:"legal_name"`
+	Email       string      `json:"email"`
+	Password    string      `json:"password"`
+	Bio         string      `json:"bio"`
+	AvatarURL   string      `json:"avatar_url"`
+	Website     string      `json:"website"`
+	SocialLinks SocialLinks `json:"social_links"`
+}
+
+// AuthorRoleInput is the body of a role change.
+type AuthorRoleInput struct {
+	Role Role `json:"role"`
 }
 
 type LoginAuthorRequest struct {
@@ -33,36 +83,76 @@ type LoginAuthorRequest struct {
 }
 
 type AuthorInputUpdate struct {
-	ID        uuid.UUID `db:"id"`
-	Name      string    `db:"name"`
-	Email     string    `db:"email"`
-	UpdatedAt time.Time `db:"updated_at"`
+	ID          uuid.UUID   `db:"id"`
+	Name        string      `db:"name"`
+	LegalName   string      `db:"legal_name"`
+	Bio         string      `db:"bio"`
+	AvatarURL   string      `db:"avatar_url"`
+	Website     string      `db:"website"`
+	SocialLinks SocialLinks `db:"social_links"`
+	UpdatedAt   time.Time   `db:"updated_at"`
+}
+
+// NewAuthorPublic maps an author to its public form; nil stays nil.
+func NewAuthorPublic(u *Author) *AuthorPublic {
+	if u == nil {
+		return nil
+	}
+	public := &AuthorPublic{
+		ID:          u.ID,
+		Name:        u.Name,
+		Slug:        u.Slug,
+		Bio:         u.Bio,
+		AvatarURL:   u.AvatarURL,
+		Website:     u.Website,
+		SocialLinks: u.SocialLinks,
+	}
+	// authors denormalized into article documents carry no dates
+	if !u.CreatedAt.IsZero() {
+		createdAt := u.CreatedAt
+		public.CreatedAt = &createdAt
+	}
+	return public
 }
 
 func (u *Author) TableName() string {
 	return "authors"
 }
 
-func CreateNewAuthor(input AuthorInput) Author {
+func CreateNewAuthor(input AuthorInput) (Author, error) {
 	password, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
 	if err != nil {
-		return Author{}
+		return Author{}, err
 	}
+	now := time.Now()
 	return Author{
-		ID:        uuid.New(),
-		Name:      input.Name,
-		Password:  string(password),
-		Email:     input.Email,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-	}
+		ID:          uuid.New(),
+		Name:        input.Name,
+		LegalName:   input.LegalName,
+		Slug:        Slugify(input.Name),
+		Bio:         input.Bio,
+		AvatarURL:   input.AvatarURL,
+		Website:     input.Website,
+		SocialLinks: input.SocialLinks,
+		Password:    string(password),
+		Email:       input.Email,
+		Role:        RoleAuthor,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+
+		VerificationSentAt: &now,
+	}, nil
 }
 
 func (u *AuthorInput) ToAuthorUpdate(id uuid.UUID) AuthorInputUpdate {
 	return AuthorInputUpdate{
-		ID:        id,
-		Name:      u.Name,
-		Email:     u.Email,
-		UpdatedAt: time.Now(),
+		ID:          id,
+		Name:        u.Name,
+		LegalName:   u.LegalName,
+		Bio:         u.Bio,
+		AvatarURL:   u.AvatarURL,
+		Website:     u.Website,
+		SocialLinks: u.SocialLinks,
+		UpdatedAt:   time.Now(),
 	}
 }
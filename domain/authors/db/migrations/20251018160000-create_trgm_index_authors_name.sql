@@ -0,0 +1,7 @@
+
+-- +migrate Up
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX idx_authors_name_trgm ON authors USING GIN (name gin_trgm_ops);
+
+-- +migrate Down
+DROP INDEX idx_authors_name_trgm;
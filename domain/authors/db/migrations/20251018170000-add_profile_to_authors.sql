@@ -0,0 +1,39 @@
+
+-- +migrate Up
+ALTER TABLE authors
+	ADD COLUMN legal_name VARCHAR(255) NOT NULL DEFAULT '',
+	-- rows inserted without a slug, outside the application, get a unique
+	-- placeholder
+	ADD COLUMN slug VARCHAR(255) DEFAULT gen_random_uuid()::text,
+	ADD COLUMN bio TEXT NOT NULL DEFAULT '',
+	ADD COLUMN avatar_url TEXT NOT NULL DEFAULT '',
+	ADD COLUMN website TEXT NOT NULL DEFAULT '',
+	ADD COLUMN social_links JSONB NOT NULL DEFAULT '{}';
+
+-- Existing authors get a slug from their name; duplicates are numbered in
+-- order of creation.
+WITH base AS (
+	SELECT id, created_at,
+		COALESCE(NULLIF(trim(BOTH '-' FROM lower(regexp_replace(name, '[^[:alnum:]]+', '-', 'g'))), ''), 'author') AS slug
+	FROM authors
+), numbered AS (
+	SELECT id, slug, row_number() OVER (PARTITION BY slug ORDER BY created_at, id) AS n
+	FROM base
+)
+UPDATE authors
+SET slug = CASE WHEN numbered.n = 1 THEN numbered.slug ELSE numbered.slug || '-' || numbered.n END
+FROM numbered
+WHERE authors.id = numbered.id;
+
+ALTER TABLE authors ALTER COLUMN slug SET NOT NULL;
+CREATE UNIQUE INDEX uq_authors_slug ON authors (slug);
+
+-- +migrate Down
+DROP INDEX uq_authors_slug;
+ALTER TABLE authors
+	DROP COLUMN social_links,
+	DROP COLUMN website,
+	DROP COLUMN avatar_url,
+	DROP COLUMN bio,
+	DROP COLUMN slug,
+	DROP COLUMN legal_name;
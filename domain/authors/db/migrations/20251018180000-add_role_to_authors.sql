@@ -0,0 +1,8 @@
+
+-- +migrate Up
+ALTER TABLE authors
+	ADD COLUMN role VARCHAR(16) NOT NULL DEFAULT 'author'
+		CHECK (role IN ('author', 'editor', 'admin'));
+
+-- +migrate Down
+ALTER TABLE authors DROP COLUMN role;
@@ -0,0 +1,30 @@
+
+-- +migrate Up
+-- access_token_id is the jti of the access token issued together with the
+-- refresh token, so revoking a family also revokes its access tokens.
+CREATE TABLE refresh_tokens (
+	id UUID PRIMARY KEY,
+	author_id UUID NOT NULL REFERENCES authors(id) ON DELETE CASCADE,
+	family_id UUID NOT NULL,
+	token_hash CHAR(64) NOT NULL UNIQUE,
+	access_token_id UUID NOT NULL,
+	access_expires_at TIMESTAMPTZ NOT NULL,
+	expires_at TIMESTAMPTZ NOT NULL,
+	used_at TIMESTAMPTZ,
+	revoked_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+CREATE INDEX idx_refresh_tokens_access_token_id ON refresh_tokens (access_token_id);
+
+-- revoked_tokens is the denylist of access tokens revoked before they
+-- expire. Rows can be dropped once expires_at has passed.
+CREATE TABLE revoked_tokens (
+	id UUID PRIMARY KEY,
+	expires_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
+
+-- +migrate Down
+DROP TABLE revoked_tokens;
+DROP TABLE refresh_tokens;
@@ -0,0 +1,22 @@
+
+-- +migrate Up
+-- Failed login counters, keyed by "email:<address>" or "ip:<address>".
+CREATE TABLE login_attempts (
+	key VARCHAR(320) PRIMARY KEY,
+	failures INTEGER NOT NULL,
+	last_failure_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE login_audit (
+	id UUID PRIMARY KEY,
+	email VARCHAR(255) NOT NULL,
+	ip VARCHAR(64) NOT NULL,
+	reason VARCHAR(32) NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_login_audit_email_created_at ON login_audit (email, created_at);
+CREATE INDEX idx_login_audit_ip_created_at ON login_audit (ip, created_at);
+
+-- +migrate Down
+DROP TABLE login_audit;
+DROP TABLE login_attempts;
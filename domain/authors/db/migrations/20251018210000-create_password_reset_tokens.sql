@@ -0,0 +1,14 @@
+
+-- +migrate Up
+CREATE TABLE password_reset_tokens (
+	id UUID PRIMARY KEY,
+	author_id UUID NOT NULL REFERENCES authors(id) ON DELETE CASCADE,
+	token_hash CHAR(64) NOT NULL UNIQUE,
+	expires_at TIMESTAMPTZ NOT NULL,
+	used_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_password_reset_tokens_author_id_created_at ON password_reset_tokens (author_id, created_at);
+
+-- +migrate Down
+DROP TABLE password_reset_tokens;
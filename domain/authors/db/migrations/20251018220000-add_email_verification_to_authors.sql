@@ -0,0 +1,15 @@
+
+-- +migrate Up
+-- Authors created before email verification existed count as verified, so
+-- they can keep publishing.
+ALTER TABLE authors
+	ADD COLUMN email_verified_at TIMESTAMPTZ,
+	ADD COLUMN pending_email VARCHAR(255),
+	ADD COLUMN verification_sent_at TIMESTAMPTZ;
+UPDATE authors SET email_verified_at = COALESCE(created_at, NOW());
+
+-- +migrate Down
+ALTER TABLE authors
+	DROP COLUMN verification_sent_at,
+	DROP COLUMN pending_email,
+	DROP COLUMN email_verified_at;
@@ -3,7 +3,20 @@ package authors
 import "github.com/afif-musyayyidin/hertz-boilerplate/domain/infra"
 
 var (
-	ErrInvalidInput = infra.New("INVALID_INPUT", "Invalid input")
-	ErrNotFound     = infra.New("NOT_FOUND", "Not found")
-	ErrInternal     = infra.New("INTERNAL_SERVER_ERROR", "Internal server error")
+	ErrInvalidInput = infra.New(infra.CodeInvalidInput, "Invalid input")
+	ErrNotFound     = infra.New(infra.CodeNotFound, "Not found")
+	ErrForbidden    = infra.New(infra.CodeForbidden, "Forbidden")
+	ErrUnauthorized = infra.New(infra.CodeUnauthorized, "Invalid or expired refresh token")
+	ErrInternal     = infra.New(infra.CodeInternalServer, "Internal server error")
+
+	ErrInvalidCredentials = infra.New(infra.CodeUnauthorized, "Invalid email or password")
+	ErrTooManyAttempts    = infra.New(infra.CodeTooManyRequests, "Too many failed login attempts")
+
+	ErrWrongPassword     = infra.New(infra.CodeForbidden, "Current password is incorrect")
+	ErrInvalidResetToken = infra.New(infra.CodeInvalidInput, "Invalid or expired reset token")
+
+	ErrInvalidVerificationToken = infra.New(infra.CodeInvalidInput, "Invalid or expired verification link")
+	ErrEmailTaken               = infra.New(infra.CodeConflict, "Email is already in use")
+	ErrEmailAlreadyVerified     = infra.New(infra.CodeConflict, "Email is already verified")
+	ErrVerificationRecentlySent = infra.New(infra.CodeTooManyRequests, "Verification email was sent recently")
 )
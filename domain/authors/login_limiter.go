@@ -0,0 +1,319 @@
+package authors
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// DefaultLoginWindow is how long failed logins are remembered after the last
+// one. It is longer than the lockout, so failing again right after a lockout
+// locks the key again.
+const DefaultLoginWindow = time.Hour
+
+// LoginPolicy decides how long a key has to wait after Failures failed
+// logins: nothing for the first FreeAttempts, then BaseDelay doubling up to
+// MaxDelay, and LockoutDuration from LockoutThreshold failures on.
+type LoginPolicy struct {
+	FreeAttempts     int
+	BaseDelay        time.Duration
+	MaxDelay         time.Duration
+	LockoutThreshold int
+	LockoutDuration  time.Duration
+}
+
+var (
+	// DefaultEmailPolicy protects one account against password guessing.
+	DefaultEmailPolicy = LoginPolicy{
+		FreeAttempts:     3,
+		BaseDelay:        time.Second,
+		MaxDelay:         time.Minute,
+		LockoutThreshold: 10,
+		LockoutDuration:  15 * time.Minute,
+	}
+	// DefaultIPPolicy is looser, as many people can share an address.
+	DefaultIPPolicy = LoginPolicy{
+		FreeAttempts:     20,
+		BaseDelay:        time.Second,
+		MaxDelay:         time.Minute,
+		LockoutThreshold: 100,
+		LockoutDuration:  15 * time.Minute,
+	}
+)
+
+// wait returns how long after now the next attempt is allowed.
+func (p LoginPolicy) wait(attempts LoginAttempts, now time.Time) time.Duration {
+	var delay time.Duration
+	switch {
+	case attempts.Failures >= p.LockoutThreshold:
+		delay = p.LockoutDuration
+	case attempts.Failures > p.FreeAttempts:
+		delay = p.MaxDelay
+		if shift := attempts.Failures - p.FreeAttempts - 1; shift < 30 && p.BaseDelay<<shift < p.MaxDelay {
+			delay = p.BaseDelay << shift
+		}
+	default:
+		return 0
+	}
+	if wait := attempts.LastFailureAt.Add(delay).Sub(now); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// LoginAttempts are the recent failed logins of one key.
+type LoginAttempts struct {
+	Failures      int       `db:"failures"`
+	LastFailureAt time.Time `db:"last_failure_at"`
+}
+
+// LoginAttemptStore keeps the failed login counters. Keys are opaque; a
+// counter is forgotten once its last failure is older than the store's
+// window.
+//
+// An attempt is counted as a failure before the password is checked, so
+// parallel guesses cannot all pass on the same count. Attempt returns the
+// counter as it stood before; Release takes the attempt back when it turns
+// out not to be a failure.
+type LoginAttemptStore interface {
+	Get(ctx context.Context, key string) (LoginAttempts, error)
+	Attempt(ctx context.Context, key string, at time.Time) (LoginAttempts, error)
+	Release(ctx context.Context, key string) error
+	Reset(ctx context.Context, key string) error
+}
+
+// MemoryLoginAttemptStore keeps the counters in the process. It is only
+// right for a single instance; counters are lost on restart. Run drops the
+// expired ones.
+type MemoryLoginAttemptStore struct {
+	window   time.Duration
+	mu       sync.Mutex
+	attempts map[string]LoginAttempts
+}
+
+func NewMemoryLoginAttemptStore(window time.Duration) *MemoryLoginAttemptStore {
+	return &MemoryLoginAttemptStore{window: window, attempts: map[string]LoginAttempts{}}
+}
+
+func (s *MemoryLoginAttemptStore) Get(ctx context.Context, key string) (LoginAttempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.current(key, time.Now()), nil
+}
+
+func (s *MemoryLoginAttemptStore) Attempt(ctx context.Context, key string, at time.Time) (LoginAttempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	before := s.current(key, at)
+	s.attempts[key] = LoginAttempts{Failures: before.Failures + 1, LastFailureAt: at}
+	return before, nil
+}
+
+func (s *MemoryLoginAttemptStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	attempts, ok := s.attempts[key]
+	if !ok {
+		return nil
+	}
+	attempts.Failures--
+	if attempts.Failures <= 0 {
+		delete(s.attempts, key)
+		return nil
+	}
+	s.attempts[key] = attempts
+	return nil
+}
+
+func (s *MemoryLoginAttemptStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.attempts, key)
+	return nil
+}
+
+// Run drops the expired counters once per window and blocks until ctx is
+// cancelled.
+func (s *MemoryLoginAttemptStore) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.sweep(time.Now())
+		}
+	}
+}
+
+func (s *MemoryLoginAttemptStore) sweep(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key := range s.attempts {
+		s.current(key, now)
+	}
+}
+
+// current returns the live counter of key and drops it when it has expired.
+func (s *MemoryLoginAttemptStore) current(key string, now time.Time) LoginAttempts {
+	attempts, ok := s.attempts[key]
+	if ok && now.Sub(attempts.LastFailureAt) > s.window {
+		delete(s.attempts, key)
+		return LoginAttempts{}
+	}
+	return attempts
+}
+
+// PostgresLoginAttemptStore keeps the counters in the login_attempts table,
+// shared by every instance.
+type PostgresLoginAttemptStore struct {
+	db     *sqlx.DB
+	window time.Duration
+}
+
+func NewPostgresLoginAttemptStore(db *sqlx.DB, window time.Duration) *PostgresLoginAttemptStore {
+	return &PostgresLoginAttemptStore{db: db, window: window}
+}
+
+func (s *PostgresLoginAttemptStore) Get(ctx context.Context, key string) (LoginAttempts, error) {
+	var attempts LoginAttempts
+	err := s.db.GetContext(ctx, &attempts, FindLoginAttemptsQuery, key, time.Now().Add(-s.window))
+	if errors.Is(err, sql.ErrNoRows) {
+		return LoginAttempts{}, nil
+	}
+	return attempts, err
+}
+
+// Attempt locks the row of key while it reads the old counter and counts the
+// new attempt, so concurrent attempts see one another.
+func (s *PostgresLoginAttemptStore) Attempt(ctx context.Context, key string, at time.Time) (LoginAttempts, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return LoginAttempts{}, err
+	}
+	if _, err := tx.ExecContext(ctx, CreateLoginAttemptsQuery, key, at); err != nil {
+		_ = tx.Rollback()
+		return LoginAttempts{}, err
+	}
+	var before LoginAttempts
+	if err := tx.GetContext(ctx, &before, FindLoginAttemptsForUpdateQuery, key); err != nil {
+		_ = tx.Rollback()
+		return LoginAttempts{}, err
+	}
+	if at.Sub(before.LastFailureAt) > s.window {
+		before = LoginAttempts{}
+	}
+	if _, err := tx.ExecContext(ctx, FailLoginAttemptQuery, key, at, at.Add(-s.window)); err != nil {
+		_ = tx.Rollback()
+		return LoginAttempts{}, err
+	}
+	return before, tx.Commit()
+}
+
+func (s *PostgresLoginAttemptStore) Release(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, ReleaseLoginAttemptQuery, key)
+	return err
+}
+
+func (s *PostgresLoginAttemptStore) Reset(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, DeleteLoginAttemptsQuery, key)
+	return err
+}
+
+// LoginLimiter throttles logins per email and per client IP. The longer of
+// the two waits applies.
+type LoginLimiter struct {
+	store       LoginAttemptStore
+	emailPolicy LoginPolicy
+	ipPolicy    LoginPolicy
+}
+
+func NewLoginLimiter(store LoginAttemptStore, emailPolicy LoginPolicy, ipPolicy LoginPolicy) *LoginLimiter {
+	return &LoginLimiter{store: store, emailPolicy: emailPolicy, ipPolicy: ipPolicy}
+}
+
+// Attempt counts a login for email from ip as failed up front and returns
+// how long it has to wait. Zero means it may go ahead: it then stays counted
+// unless Succeed is called. An attempt that has to wait is not counted.
+func (l *LoginLimiter) Attempt(ctx context.Context, email string, ip string) (time.Duration, error) {
+	now := time.Now()
+	byEmail, err := l.store.Attempt(ctx, emailKey(email), now)
+	if err != nil {
+		return 0, err
+	}
+	wait := l.emailPolicy.wait(byEmail, now)
+	if ip != "" {
+		byIP, err := l.store.Attempt(ctx, ipKey(ip), now)
+		if err != nil {
+			return 0, err
+		}
+		if ipWait := l.ipPolicy.wait(byIP, now); ipWait > wait {
+			wait = ipWait
+		}
+	}
+	if wait > 0 {
+		return wait, l.release(ctx, email, ip)
+	}
+	return 0, nil
+}
+
+// Succeed clears the counter of email after a successful attempt. The IP
+// counter only gives the attempt back, otherwise an attacker could clear it
+// by logging in to an account of their own between guesses.
+func (l *LoginLimiter) Succeed(ctx context.Context, email string, ip string) error {
+	if err := l.store.Reset(ctx, emailKey(email)); err != nil {
+		return err
+	}
+	if ip == "" {
+		return nil
+	}
+	return l.store.Release(ctx, ipKey(ip))
+}
+
+// Clear forgets the failed logins of email, for when the account owner has
+// proven themselves some other way.
+func (l *LoginLimiter) Clear(ctx context.Context, email string) error {
+	return l.store.Reset(ctx, emailKey(email))
+}
+
+func (l *LoginLimiter) release(ctx context.Context, email string, ip string) error {
+	if err := l.store.Release(ctx, emailKey(email)); err != nil {
+		return err
+	}
+	if ip == "" {
+		return nil
+	}
+	return l.store.Release(ctx, ipKey(ip))
+}
+
+func emailKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// Reasons a login attempt failed, as recorded in login_audit.
+const (
+	LoginFailureUnknownEmail  = "unknown_email"
+	LoginFailureWrongPassword = "wrong_password"
+	LoginFailureThrottled     = "throttled"
+)
+
+// LoginAudit is the record of one failed login.
+type LoginAudit struct {
+	ID        uuid.UUID `db:"id"`
+	Email     string    `db:"email"`
+	IP        string    `db:"ip"`
+	Reason    string    `db:"reason"`
+	CreatedAt time.Time `db:"created_at"`
+}
@@ -2,8 +2,13 @@ package authors
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"strings"
 	"time"
 
+	"github.com/afif-musyayyidin/hertz-boilerplate/domain/infra/logger"
 	"github.com/afif-musyayyidin/hertz-boilerplate/middleware"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
@@ -12,20 +17,37 @@ import (
 
 type AuthorMutation interface {
 	CreateAuthor(ctx context.Context, u *AuthorInput) (*uuid.UUID, error)
-	UpdateAuthor(ctx context.Context, u *AuthorInput, id uuid.UUID) (*uuid.UUID, error)
+	UpdateAuthor(ctx context.Context, u *AuthorInput, id uuid.UUID, actorID uuid.UUID) (*uuid.UUID, error)
+	SetAuthorRole(ctx context.Context, id uuid.UUID, role Role, actorID uuid.UUID) error
 	GetAuthorByID(ctx context.Context, id uuid.UUID) (*Author, error)
-	LoginAuthor(ctx context.Context, email string, password string) (*string, error)
+	GetAuthorRole(ctx context.Context, id uuid.UUID) (Role, error)
+	GetAuthorBySlug(ctx context.Context, slug string) (*Author, error)
+	LoginAuthor(ctx context.Context, email string, password string, ip string) (*TokenPair, error)
+	RefreshToken(ctx context.Context, refreshToken string) (*TokenPair, error)
+	Logout(ctx context.Context, authorID uuid.UUID, tokenID uuid.UUID, expiresAt time.Time) error
+	ChangePassword(ctx context.Context, authorID uuid.UUID, currentPassword string, newPassword string, ip string) (*TokenPair, error)
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token string, newPassword string) error
+	VerifyEmail(ctx context.Context, token string) error
+	ResendVerification(ctx context.Context, authorID uuid.UUID) error
 	GetAuthorByIDList(ctx context.Context, idList []uuid.UUID) ([]Author, error)
 	FindIDNameByName(ctx context.Context, name string) ([]*AuthorIDName, error)
+	SuggestAuthorByName(ctx context.Context, prefix string, limit int) ([]*AuthorSuggestion, error)
+	ListAuthors(ctx context.Context, page PageRequest) (*AuthorPage, error)
+	SearchAuthors(ctx context.Context, name string, page PageRequest) (*AuthorPage, error)
 }
 
 type authorMutation struct {
-	repo AuthorRepository
-	db   *sqlx.DB
+	repo     AuthorRepository
+	db       *sqlx.DB
+	limiter  *LoginLimiter
+	mail     *AccountMail
+	verifier *EmailVerifier
+	keys     *middleware.KeySet
 }
 
-func NewAuthorMutation(repo AuthorRepository, db *sqlx.DB) AuthorMutation {
-	return &authorMutation{repo: repo, db: db}
+func NewAuthorMutation(repo AuthorRepository, db *sqlx.DB, limiter *LoginLimiter, mail *AccountMail, verifier *EmailVerifier, keys *middleware.KeySet) AuthorMutation {
+	return &authorMutation{repo: repo, db: db, limiter: limiter, mail: mail, verifier: verifier, keys: keys}
 }
 
 func (m *authorMutation) CreateAuthor(ctx context.Context, u *AuthorInput) (*uuid.UUID, error) {
@@ -36,11 +58,20 @@ func (m *authorMutation) CreateAuthor(ctx context.Context, u *AuthorInput) (*uui
 	if u.Name == "" || u.Email == "" {
 		return nil, ErrInvalidInput
 	}
+	if err := validateEmail(u.Email); err != nil {
+		return nil, err
+	}
+	if err := validatePassword(u.Password); err != nil {
+		return nil, err
+	}
+	if err := validateProfile(u); err != nil {
+		return nil, err
+	}
 	tx, err := m.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
-	id, err := m.repo.Save(ctx, u)
+	id, err := m.repo.Save(ctx, u, tx)
 	if err != nil {
 		_ = tx.Rollback()
 		return nil, err
@@ -48,54 +79,673 @@ func (m *authorMutation) CreateAuthor(ctx context.Context, u *AuthorInput) (*uui
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
+	m.sendVerification(ctx, &Author{ID: *id, Name: u.Name, Email: u.Email}, u.Email)
 	return id, nil
 }
 
-func (m *authorMutation) UpdateAuthor(ctx context.Context, u *AuthorInput, id uuid.UUID) (*uuid.UUID, error) {
+// UpdateAuthor changes the account of author id on behalf of actorID, who
+// must be that author or an admin. A new email is only pending until it is
+// verified; an empty one keeps the current email.
+func (m *authorMutation) UpdateAuthor(ctx context.Context, u *AuthorInput, id uuid.UUID, actorID uuid.UUID) (*uuid.UUID, error) {
 	if u == nil {
 		return nil, ErrInvalidInput
 	}
-	if id == uuid.Nil {
+	if id == uuid.Nil || u.Name == "" {
 		return nil, ErrInvalidInput
 	}
+	if err := m.authorize(ctx, id, actorID); err != nil {
+		return nil, err
+	}
+	if err := validateProfile(u); err != nil {
+		return nil, err
+	}
+	email := strings.TrimSpace(u.Email)
+	if email != "" {
+		if err := validateEmail(email); err != nil {
+			return nil, err
+		}
+	}
 	tx, err := m.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
-	idResult, err := m.repo.Update(ctx, u, id)
+	current, err := m.repo.FindForUpdate(ctx, id, tx)
+	if errors.Is(err, sql.ErrNoRows) {
+		_ = tx.Rollback()
+		return nil, ErrNotFound.WithDetails(map[string]interface{}{
+			"id": id,
+		})
+	}
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	idResult, err := m.repo.Update(ctx, u, id, tx)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	verify, err := m.changeEmail(ctx, current, email, tx)
 	if err != nil {
 		_ = tx.Rollback()
 		return nil, err
 	}
+	// article documents carry a copy of the author
+	if err := m.repo.EnqueueSync(ctx, id, tx); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
+	if verify {
+		m.sendVerification(ctx, current, email)
+	}
 	return idResult, nil
 }
 
+// changeEmail starts or cancels a change of the email of current to email.
+// It reports whether a verification link has to be sent to email.
+func (m *authorMutation) changeEmail(ctx context.Context, current *Author, email string, tx *sqlx.Tx) (bool, error) {
+	switch {
+	case email == "" || email == current.Email:
+		if current.PendingEmail == nil {
+			return false, nil
+		}
+		return false, m.repo.SetPendingEmail(ctx, current.ID, nil, tx)
+	case current.PendingEmail != nil && *current.PendingEmail == email:
+		return false, nil
+	}
+	taken, err := m.repo.EmailInUse(ctx, email, current.ID, tx)
+	if err != nil {
+		return false, err
+	}
+	if taken {
+		return false, ErrEmailTaken.WithDetails(map[string]interface{}{
+			"email": email,
+		})
+	}
+	if err := m.repo.SetPendingEmail(ctx, current.ID, &email, tx); err != nil {
+		return false, err
+	}
+	if err := m.repo.MarkVerificationSent(ctx, current.ID, time.Now(), tx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// VerifyEmail confirms the address named by a verification link. A link for
+// the pending email completes the change, a link for the current email marks
+// it verified; links for any other address have been superseded.
+func (m *authorMutation) VerifyEmail(ctx context.Context, token string) error {
+	id, email, err := m.verifier.Verify(token, time.Now())
+	if err != nil {
+		return err
+	}
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	author, err := m.repo.FindForUpdate(ctx, id, tx)
+	if errors.Is(err, sql.ErrNoRows) {
+		_ = tx.Rollback()
+		return ErrInvalidVerificationToken
+	}
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	pending := author.PendingEmail != nil && *author.PendingEmail == email
+	switch {
+	case pending, email == author.Email && !author.EmailVerified():
+		if err := m.repo.VerifyEmail(ctx, id, email, tx); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	case email == author.Email:
+		// already verified, the link was opened twice
+		_ = tx.Rollback()
+		return nil
+	default:
+		_ = tx.Rollback()
+		return ErrInvalidVerificationToken
+	}
+	return tx.Commit()
+}
+
+// ResendVerification sends a new link for the pending email of authorID, or
+// for the current one while it is unverified, at most once per
+// EmailVerificationInterval.
+func (m *authorMutation) ResendVerification(ctx context.Context, authorID uuid.UUID) error {
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	author, err := m.repo.FindForUpdate(ctx, authorID, tx)
+	if errors.Is(err, sql.ErrNoRows) {
+		_ = tx.Rollback()
+		return ErrNotFound.WithDetails(map[string]interface{}{
+			"id": authorID,
+		})
+	}
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	email := author.Email
+	if author.PendingEmail != nil {
+		email = *author.PendingEmail
+	} else if author.EmailVerified() {
+		_ = tx.Rollback()
+		return ErrEmailAlreadyVerified
+	}
+	now := time.Now()
+	if author.VerificationSentAt != nil {
+		if wait := author.VerificationSentAt.Add(EmailVerificationInterval).Sub(now); wait > 0 {
+			_ = tx.Rollback()
+			return ErrVerificationRecentlySent.WithDetails(map[string]interface{}{
+				"retry_after": int(math.Ceil(wait.Seconds())),
+			})
+		}
+	}
+	if err := m.repo.MarkVerificationSent(ctx, authorID, now, tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	m.sendVerification(ctx, author, email)
+	return nil
+}
+
+// sendVerification mails author a verification link for email. A failed
+// send is only logged; the author can ask for the link again.
+func (m *authorMutation) sendVerification(ctx context.Context, author *Author, email string) {
+	token, err := m.verifier.Sign(author.ID, email, time.Now())
+	if err == nil {
+		err = m.mail.SendEmailVerification(ctx, author, email, token)
+	}
+	if err != nil {
+		logger.Debug("error send email verification", err)
+	}
+}
+
+// SetAuthorRole gives author id a new role. Only admins may change roles,
+// and not their own, so the last admin cannot lock everyone out.
+func (m *authorMutation) SetAuthorRole(ctx context.Context, id uuid.UUID, role Role, actorID uuid.UUID) error {
+	if !role.Valid() {
+		return ErrInvalidInput.WithDetails(map[string]interface{}{
+			"role": role,
+		})
+	}
+	if id == actorID {
+		return ErrForbidden.WithDetails(map[string]interface{}{
+			"id": id,
+		})
+	}
+	if err := m.authorize(ctx, id, actorID); err != nil {
+		return err
+	}
+	err := m.repo.UpdateRole(ctx, id, role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound.WithDetails(map[string]interface{}{
+			"id": id,
+		})
+	}
+	return err
+}
+
+// authorize checks that actorID may change the account of author id: it is
+// their own account or actorID is an admin. The role is read from the
+// primary, so a revoked role takes effect before the actor's token expires.
+func (m *authorMutation) authorize(ctx context.Context, id uuid.UUID, actorID uuid.UUID) error {
+	if actorID == id {
+		return nil
+	}
+	forbidden := ErrForbidden.WithDetails(map[string]interface{}{
+		"id": id,
+	})
+	if actorID == uuid.Nil {
+		return forbidden
+	}
+	role, err := m.repo.FindRole(ctx, actorID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return forbidden
+	}
+	if err != nil {
+		return err
+	}
+	if !role.CanManageAuthors() {
+		return forbidden
+	}
+	return nil
+}
+
 func (m *authorMutation) FindIDNameByName(ctx context.Context, name string) ([]*AuthorIDName, error) {
 	return m.repo.FindIDNameByName(ctx, name)
 }
 
+func (m *authorMutation) SuggestAuthorByName(ctx context.Context, prefix string, limit int) ([]*AuthorSuggestion, error) {
+	return m.repo.SuggestByName(ctx, prefix, limit)
+}
+
 func (m *authorMutation) GetAuthorByID(ctx context.Context, id uuid.UUID) (*Author, error) {
-	return m.repo.FindByID(ctx, id)
+	author, err := m.repo.FindByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound.WithDetails(map[string]interface{}{
+			"id": id,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return author, nil
+}
+
+// GetAuthorRole returns the role of author id as the primary has it, for
+// permission checks that must not act on a stale replica.
+func (m *authorMutation) GetAuthorRole(ctx context.Context, id uuid.UUID) (Role, error) {
+	role, err := m.repo.FindRole(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNotFound.WithDetails(map[string]interface{}{
+			"id": id,
+		})
+	}
+	return role, err
+}
+
+func (m *authorMutation) GetAuthorBySlug(ctx context.Context, slug string) (*Author, error) {
+	author, err := m.repo.FindBySlug(ctx, slug)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound.WithDetails(map[string]interface{}{
+			"slug": slug,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return author, nil
+}
+
+func (m *authorMutation) ListAuthors(ctx context.Context, page PageRequest) (*AuthorPage, error) {
+	page.Name = ""
+	return m.repo.FindPage(ctx, page)
+}
+
+// SearchAuthors lists the authors whose name contains name, ignoring case.
+func (m *authorMutation) SearchAuthors(ctx context.Context, name string, page PageRequest) (*AuthorPage, error) {
+	page.Name = strings.TrimSpace(name)
+	if page.Name == "" {
+		return nil, ErrInvalidInput.WithDetails(map[string]interface{}{
+			"q": name,
+		})
+	}
+	return m.repo.FindPage(ctx, page)
 }
 
 func (m *authorMutation) GetAuthorByIDList(ctx context.Context, idList []uuid.UUID) ([]Author, error) {
 	return m.repo.FindByIDList(ctx, idList)
 }
 
-func (m *authorMutation) LoginAuthor(ctx context.Context, email string, password string) (*string, error) {
-	author, err := m.repo.FindByEmail(ctx, email)
+// LoginAuthor checks the password and starts a new refresh token family.
+// An unknown email and a wrong password give the same error and take about
+// the same time, so the response does not tell which emails exist. Failed
+// attempts are throttled per email and per ip and recorded in login_audit.
+func (m *authorMutation) LoginAuthor(ctx context.Context, email string, password string, ip string) (*TokenPair, error) {
+	wait, err := m.limiter.Attempt(ctx, email, ip)
 	if err != nil {
 		return nil, err
 	}
+	if wait > 0 {
+		m.auditLogin(ctx, email, ip, LoginFailureThrottled)
+		return nil, ErrTooManyAttempts.WithDetails(map[string]interface{}{
+			"retry_after": int(math.Ceil(wait.Seconds())),
+		})
+	}
+
+	author, err := m.repo.FindByEmail(ctx, email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if author == nil {
+		_ = bcrypt.CompareHashAndPassword([]byte(dummyPasswordHash), []byte(password))
+		return nil, m.failLogin(ctx, email, ip, LoginFailureUnknownEmail)
+	}
 	if err := bcrypt.CompareHashAndPassword([]byte(author.Password), []byte(password)); err != nil {
+		return nil, m.failLogin(ctx, email, ip, LoginFailureWrongPassword)
+	}
+	if err := m.limiter.Succeed(ctx, email, ip); err != nil {
+		return nil, err
+	}
+
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	pair, err := m.issueTokens(ctx, author, uuid.New(), tx)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// RefreshToken exchanges a refresh token for a new pair. Every refresh token
+// works once: presenting one again means it was stolen, so the whole family
+// is revoked, including the access tokens issued with it, and the caller has
+// to log in again.
+func (m *authorMutation) RefreshToken(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	if refreshToken == "" {
+		return nil, ErrInvalidInput.WithDetails(map[string]interface{}{
+			"refresh_token": "required",
+		})
+	}
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := m.repo.FindRefreshTokenForUpdate(ctx, hashToken(refreshToken), tx)
+	if errors.Is(err, sql.ErrNoRows) {
+		_ = tx.Rollback()
+		return nil, ErrUnauthorized
+	}
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if stored.UsedAt != nil || stored.RevokedAt != nil {
+		if err := m.repo.RevokeTokenFamily(ctx, stored.FamilyID, tx); err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+		return nil, ErrUnauthorized
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		_ = tx.Rollback()
+		return nil, ErrUnauthorized
+	}
+	// the new access token carries the current name and role
+	author, err := m.repo.FindByID(ctx, stored.AuthorID)
+	if errors.Is(err, sql.ErrNoRows) {
+		_ = tx.Rollback()
+		return nil, ErrUnauthorized
+	}
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err := m.repo.MarkRefreshTokenUsed(ctx, stored.ID, tx); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	pair, err := m.issueTokens(ctx, author, stored.FamilyID, tx)
+	if err != nil {
+		_ = tx.Rollback()
 		return nil, err
 	}
-	token, err := middleware.GenerateToken(author.ID.String(), author.Name, author.Email, 24*time.Hour)
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// Logout revokes the access token tokenID and the refresh token family it
+// was issued with.
+func (m *authorMutation) Logout(ctx context.Context, authorID uuid.UUID, tokenID uuid.UUID, expiresAt time.Time) error {
+	tx, err := m.db.BeginTxx(ctx, nil)
 	if err != nil {
+		return err
+	}
+	familyID, err := m.repo.FindTokenFamily(ctx, authorID, tokenID, tx)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		_ = tx.Rollback()
+		return err
+	}
+	if err == nil {
+		if err := m.repo.RevokeTokenFamily(ctx, familyID, tx); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	if err := m.repo.RevokeAccessToken(ctx, tokenID, expiresAt, tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// ChangePassword replaces the password of authorID after checking the
+// current one. Every session of the author is revoked, this one included, and
+// a new pair is returned so the caller stays logged in. A wrong current
+// password counts as a failed login.
+func (m *authorMutation) ChangePassword(ctx context.Context, authorID uuid.UUID, currentPassword string, newPassword string, ip string) (*TokenPair, error) {
+	if err := validatePassword(newPassword); err != nil {
 		return nil, err
 	}
-	return &token, nil
-}
\ No newline at end of file
+	author, err := m.GetAuthorByID(ctx, authorID)
+	if err != nil {
+		return nil, err
+	}
+	wait, err := m.limiter.Attempt(ctx, author.Email, ip)
+	if err != nil {
+		return nil, err
+	}
+	if wait > 0 {
+		m.auditLogin(ctx, author.Email, ip, LoginFailureThrottled)
+		return nil, ErrTooManyAttempts.WithDetails(map[string]interface{}{
+			"retry_after": int(math.Ceil(wait.Seconds())),
+		})
+	}
+	hash, err := m.repo.FindPassword(ctx, authorID)
+	if err != nil {
+		return nil, err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(currentPassword)); err != nil {
+		m.auditLogin(ctx, author.Email, ip, LoginFailureWrongPassword)
+		return nil, ErrWrongPassword
+	}
+	if err := m.limiter.Succeed(ctx, author.Email, ip); err != nil {
+		return nil, err
+	}
+
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.setPassword(ctx, authorID, newPassword, tx); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	pair, err := m.issueTokens(ctx, author, uuid.New(), tx)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// ForgotPassword mails a reset link to the author with email. It answers the
+// same whether the email exists or not, and sends at most one link per
+// PasswordResetInterval. Issuing a link invalidates the previous ones.
+func (m *authorMutation) ForgotPassword(ctx context.Context, email string) error {
+	if strings.TrimSpace(email) == "" {
+		return ErrInvalidInput.WithDetails(map[string]interface{}{
+			"email": "required",
+		})
+	}
+	author, err := m.repo.FindByEmail(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	recent, err := m.repo.HasRecentPasswordReset(ctx, author.ID, time.Now().Add(-PasswordResetInterval), tx)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if recent {
+		_ = tx.Rollback()
+		return nil
+	}
+	if err := m.repo.UsePasswordResetTokens(ctx, author.ID, tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	token, hash, err := newRefreshToken()
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	now := time.Now()
+	err = m.repo.SavePasswordResetToken(ctx, &PasswordResetToken{
+		ID:        uuid.New(),
+		AuthorID:  author.ID,
+		TokenHash: hash,
+		ExpiresAt: now.Add(PasswordResetTTL),
+		CreatedAt: now,
+	}, tx)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	// a failed send is not reported to the caller, that would tell the
+	// email exists
+	if err := m.mail.SendPasswordReset(ctx, author, token); err != nil {
+		logger.Debug("error send password reset", err)
+	}
+	return nil
+}
+
+// ResetPassword sets a new password with a token from ForgotPassword. The
+// token works once; every session of the author is revoked.
+func (m *authorMutation) ResetPassword(ctx context.Context, token string, newPassword string) error {
+	if token == "" {
+		return ErrInvalidResetToken
+	}
+	if err := validatePassword(newPassword); err != nil {
+		return err
+	}
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	stored, err := m.repo.FindPasswordResetTokenForUpdate(ctx, hashToken(token), tx)
+	if errors.Is(err, sql.ErrNoRows) {
+		_ = tx.Rollback()
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if stored.UsedAt != nil || time.Now().After(stored.ExpiresAt) {
+		_ = tx.Rollback()
+		return ErrInvalidResetToken
+	}
+	if err := m.repo.UsePasswordResetTokens(ctx, stored.AuthorID, tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := m.setPassword(ctx, stored.AuthorID, newPassword, tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	// whoever reset the password owns the mailbox, so an earlier lockout of
+	// the account no longer applies
+	return m.limiter.Clear(ctx, stored.Email)
+}
+
+// setPassword stores password for author id and revokes all of their
+// sessions.
+func (m *authorMutation) setPassword(ctx context.Context, id uuid.UUID, password string, tx *sqlx.Tx) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := m.repo.UpdatePassword(ctx, id, string(hash), tx); err != nil {
+		return err
+	}
+	return m.repo.RevokeAuthorTokens(ctx, id, tx)
+}
+
+// issueTokens signs an access token for author and stores a refresh token
+// of family familyID next to it.
+func (m *authorMutation) issueTokens(ctx context.Context, author *Author, familyID uuid.UUID, tx *sqlx.Tx) (*TokenPair, error) {
+	accessTokenID := uuid.New()
+	accessToken, err := m.keys.GenerateToken(author.ID.String(), author.Name, author.Email, string(author.Role), accessTokenID.String(), AccessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, hash, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	err = m.repo.SaveRefreshToken(ctx, &RefreshToken{
+		ID:              uuid.New(),
+		AuthorID:        author.ID,
+		FamilyID:        familyID,
+		TokenHash:       hash,
+		AccessTokenID:   accessTokenID,
+		AccessExpiresAt: now.Add(AccessTokenTTL),
+		ExpiresAt:       now.Add(RefreshTokenTTL),
+	}, tx)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+// dummyPasswordHash is compared against when the email is unknown, so that
+// answer takes as long as a wrong password.
+const dummyPasswordHash = "$2a$10$IbqNIFYX8z1iKi7ACariS.5caKL64bohzebJaY0UmVOr0sJuufgxC"
+
+// failLogin records a failed login and returns the error for the caller. The
+// limiter counted the attempt already.
+func (m *authorMutation) failLogin(ctx context.Context, email string, ip string, reason string) error {
+	m.auditLogin(ctx, email, ip, reason)
+	return ErrInvalidCredentials
+}
+
+// auditLogin records a failed login. A failing audit write is logged but does
+// not change the answer to the caller.
+func (m *authorMutation) auditLogin(ctx context.Context, email string, ip string, reason string) {
+	err := m.repo.SaveLoginAudit(ctx, &LoginAudit{
+		ID:        uuid.New(),
+		Email:     email,
+		IP:        ip,
+		Reason:    reason,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		logger.Debug("error save login audit", err)
+	}
+}
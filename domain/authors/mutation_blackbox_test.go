@@ -2,13 +2,22 @@ package authors_test
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/rand"
 	"fmt"
 	"log"
 	"os"
+	"regexp"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/afif-musyayyidin/hertz-boilerplate/config"
 	"github.com/afif-musyayyidin/hertz-boilerplate/domain/authors"
+	"github.com/afif-musyayyidin/hertz-boilerplate/domain/infra"
+	"github.com/afif-musyayyidin/hertz-boilerplate/middleware"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"github.com/olivere/elastic"
@@ -19,8 +28,19 @@ var (
 	testDB *sqlx.DB
 	es     *elastic.Client
 	ctx    = context.Background()
+	sent   = &mailbox{}
 )
 
+// mailbox records the mails instead of sending them.
+type mailbox struct {
+	mails []infra.Mail
+}
+
+func (b *mailbox) Send(ctx context.Context, mail infra.Mail) error {
+	b.mails = append(b.mails, mail)
+	return nil
+}
+
 func TestMain(m *testing.M) {
 	// load config
 	cfg := config.LoadConfig()
@@ -65,14 +85,41 @@ func cleanDB() {
 
 func newMutation() authors.AuthorMutation {
 	repo := authors.NewAuthorRepo(testDB, testDB)
-	return authors.NewAuthorMutation(repo, testDB)
+	mail := authors.NewAccountMail(sent, "http://localhost:3000/reset-password", "http://localhost:8080/author/verify")
+	return authors.NewAuthorMutation(repo, testDB, newLoginLimiter(), mail, newEmailVerifier(), newKeySet())
+}
+
+func newKeySet() *middleware.KeySet {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		log.Fatal(err)
+	}
+	keys, err := middleware.NewKeySet("test", private, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return keys
+}
+
+func newEmailVerifier() *authors.EmailVerifier {
+	verifier, err := authors.NewEmailVerifier([]byte(strings.Repeat("k", 32)))
+	if err != nil {
+		log.Fatal(err)
+	}
+	return verifier
+}
+
+func newLoginLimiter() *authors.LoginLimiter {
+	store := authors.NewMemoryLoginAttemptStore(authors.DefaultLoginWindow)
+	return authors.NewLoginLimiter(store, authors.DefaultEmailPolicy, authors.DefaultIPPolicy)
 }
 func TestCreateAuthor(t *testing.T) {
 	mutation := newMutation()
 
 	input := authors.AuthorInput{
-		Name:  "John Doe",
-		Email: "john@example.com",
+		Name:     "John Doe",
+		Email:    "john@example.com",
+		Password: "rahasia123",
 	}
 
 	id, err := mutation.CreateAuthor(ctx, &input)
@@ -83,6 +130,16 @@ func TestCreateAuthor(t *testing.T) {
 	err = testDB.Get(&name, "SELECT name FROM authors WHERE id = $1", *id)
 	assert.NoError(t, err)
 	assert.Equal(t, "John Doe", name)
+
+	// passwords are checked before anything is stored
+	for _, password := range []string{"", "pendek", strings.Repeat("x", authors.MaxPasswordLength+1)} {
+		_, err = mutation.CreateAuthor(ctx, &authors.AuthorInput{Name: "John Doe", Email: "john2@example.com", Password: password})
+		assert.ErrorContains(t, err, "INVALID_INPUT")
+	}
+	var count int
+	err = testDB.Get(&count, "SELECT COUNT(*) FROM authors WHERE email = $1 OR id = $2", "john2@example.com", uuid.Nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
 }
 
 func TestUpdateAuthor(t *testing.T) {
@@ -101,7 +158,7 @@ func TestUpdateAuthor(t *testing.T) {
 		Email: "jane_updated@example.com",
 	}
 
-	id, err := mutation.UpdateAuthor(ctx, &input, authorID)
+	id, err := mutation.UpdateAuthor(ctx, &input, authorID, authorID)
 	assert.NoError(t, err)
 	assert.NotNil(t, id)
 
@@ -152,3 +209,521 @@ func TestFindIDNameByName(t *testing.T) {
 	assert.Len(t, result, 1)
 	assert.Equal(t, name, result[0].Name)
 }
+
+func TestSuggestAuthorByName(t *testing.T) {
+	cleanDB()
+	mutation := newMutation()
+
+	for _, name := range []string{"Bella Swan", "Isabella", "Budi"} {
+		_, err := testDB.Exec(
+			`INSERT INTO authors (id, name, email) VALUES ($1, $2, $3)`,
+			uuid.New(), name, uuid.NewString()+"@example.com",
+		)
+		assert.NoError(t, err)
+	}
+
+	result, err := mutation.SuggestAuthorByName(ctx, "bel", 10)
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+	assert.Equal(t, "Bella Swan", result[0].Name)
+	assert.Equal(t, "Isabella", result[1].Name)
+	assert.Greater(t, result[0].Score, result[1].Score)
+
+	result, err = mutation.SuggestAuthorByName(ctx, "%", 10)
+	assert.NoError(t, err)
+	assert.Empty(t, result)
+}
+
+func TestListAndSearchAuthors(t *testing.T) {
+	cleanDB()
+	mutation := newMutation()
+
+	for n, name := range []string{"Citra", "Siti Aminah", "Budi", "Ahmad Situmorang", "Dewi"} {
+		_, err := testDB.Exec(
+			`INSERT INTO authors (id, name, email, created_at) VALUES ($1, $2, $3, $4)`,
+			uuid.New(), name, uuid.NewString()+"@example.com", time.Now().Add(time.Duration(n)*time.Minute),
+		)
+		assert.NoError(t, err)
+	}
+
+	var names []string
+	page := authors.PageRequest{Limit: 2}
+	for {
+		result, err := mutation.ListAuthors(ctx, page)
+		assert.NoError(t, err)
+		for _, author := range result.Authors {
+			names = append(names, author.Name)
+		}
+		if result.NextCursor == "" {
+			break
+		}
+		page.Cursor = result.NextCursor
+	}
+	assert.Equal(t, []string{"Ahmad Situmorang", "Budi", "Citra", "Dewi", "Siti Aminah"}, names)
+
+	result, err := mutation.ListAuthors(ctx, authors.PageRequest{Sort: authors.SortByCreatedAtDesc, Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, "Dewi", result.Authors[0].Name)
+	assert.Equal(t, "Ahmad Situmorang", result.Authors[1].Name)
+	assert.NotEmpty(t, result.NextCursor)
+
+	_, err = mutation.ListAuthors(ctx, authors.PageRequest{Sort: "email"})
+	assert.ErrorContains(t, err, "INVALID_INPUT")
+
+	result, err = mutation.SearchAuthors(ctx, "SIT", authors.PageRequest{})
+	assert.NoError(t, err)
+	assert.Len(t, result.Authors, 2)
+	assert.Equal(t, "Ahmad Situmorang", result.Authors[0].Name)
+	assert.Equal(t, "Siti Aminah", result.Authors[1].Name)
+
+	idNameList, err := mutation.FindIDNameByName(ctx, "sit")
+	assert.NoError(t, err)
+	assert.Len(t, idNameList, 2)
+
+	_, err = mutation.SearchAuthors(ctx, " ", authors.PageRequest{})
+	assert.ErrorContains(t, err, "INVALID_INPUT")
+
+	_, err = mutation.GetAuthorByID(ctx, uuid.New())
+	assert.ErrorContains(t, err, "NOT_FOUND")
+}
+
+func TestAuthorProfile(t *testing.T) {
+	cleanDB()
+	mutation := newMutation()
+
+	first, err := mutation.CreateAuthor(ctx, &authors.AuthorInput{
+		Name:      "Budi Santoso",
+		LegalName: "Budi Santoso Wibowo",
+		Email:     "budi@example.com",
+		Password:  "rahasia123",
+		Bio:       "Menulis tentang Go",
+		AvatarURL: "https://cdn.example.com/budi.png",
+		SocialLinks: authors.SocialLinks{
+			"github": "https://github.com/budi",
+		},
+	})
+	assert.NoError(t, err)
+	second, err := mutation.CreateAuthor(ctx, &authors.AuthorInput{Name: "Budi  Santoso!", Email: "budi2@example.com", Password: "rahasia123"})
+	assert.NoError(t, err)
+
+	author, err := mutation.GetAuthorByID(ctx, *first)
+	assert.NoError(t, err)
+	assert.Equal(t, "budi-santoso", author.Slug)
+	assert.Equal(t, "Budi Santoso Wibowo", author.LegalName)
+	assert.Equal(t, "https://github.com/budi", author.SocialLinks["github"])
+
+	author, err = mutation.GetAuthorBySlug(ctx, "budi-santoso-2")
+	assert.NoError(t, err)
+	assert.Equal(t, *second, author.ID)
+
+	_, err = mutation.UpdateAuthor(ctx, &authors.AuthorInput{
+		Name:      "Budi S.",
+		Email:     "budi@example.com",
+		AvatarURL: "https://cdn.example.com/budi-new.png",
+	}, *first, *first)
+	assert.NoError(t, err)
+	author, err = mutation.GetAuthorByID(ctx, *first)
+	assert.NoError(t, err)
+	assert.Equal(t, "budi-santoso", author.Slug)
+	assert.Equal(t, "https://cdn.example.com/budi-new.png", author.AvatarURL)
+
+	_, err = mutation.UpdateAuthor(ctx, &authors.AuthorInput{Email: "budi@example.com"}, *first, *first)
+	assert.ErrorContains(t, err, "INVALID_INPUT")
+
+	var events int
+	err = testDB.Get(&events, "SELECT COUNT(*) FROM outbox WHERE aggregate_id = $1 AND event_type = $2", *first, authors.EventAuthorSync)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, events)
+
+	_, err = mutation.CreateAuthor(ctx, &authors.AuthorInput{Name: "Eko", Email: "eko@example.com", Password: "rahasia123", Website: "javascript:alert(1)"})
+	assert.ErrorContains(t, err, "INVALID_INPUT")
+
+	public := authors.NewAuthorPublic(author)
+	assert.Equal(t, "budi-santoso", public.Slug)
+	assert.Equal(t, "https://cdn.example.com/budi-new.png", public.AvatarURL)
+}
+
+func TestAuthorSlugParallel(t *testing.T) {
+	cleanDB()
+	mutation := newMutation()
+
+	// authors created at once with the same name still get their own slug
+	var wg sync.WaitGroup
+	ids := make(chan uuid.UUID, 5)
+	for i := 0; i < cap(ids); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id, err := mutation.CreateAuthor(ctx, &authors.AuthorInput{Name: "Sari Dewi", Email: fmt.Sprintf("sari%d@example.com", i), Password: "rahasia123"})
+			if assert.NoError(t, err) {
+				ids <- *id
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(ids)
+
+	slugs := map[string]bool{}
+	for id := range ids {
+		author, err := mutation.GetAuthorByID(ctx, id)
+		assert.NoError(t, err)
+		slugs[author.Slug] = true
+	}
+	assert.Len(t, slugs, 5)
+	assert.True(t, slugs["sari-dewi"])
+}
+
+func TestAuthorRoles(t *testing.T) {
+	cleanDB()
+	mutation := newMutation()
+
+	jane, budi, admin := uuid.New(), uuid.New(), uuid.New()
+	_, err := testDB.Exec(
+		`INSERT INTO authors (id, name, email, role) VALUES ($1, $2, $3, 'author'), ($4, $5, $6, 'author'), ($7, $8, $9, 'admin')`,
+		jane, "Jane", "jane@example.com",
+		budi, "Budi", "budi@example.com",
+		admin, "Admin", "admin@example.com",
+	)
+	assert.NoError(t, err)
+
+	// an author cannot change someone else's account
+	_, err = mutation.UpdateAuthor(ctx, &authors.AuthorInput{Name: "Hacked", Email: "evil@example.com"}, jane, budi)
+	assert.ErrorContains(t, err, "FORBIDDEN")
+
+	// an admin can
+	_, err = mutation.UpdateAuthor(ctx, &authors.AuthorInput{Name: "Jane Doe", Email: "jane@example.com"}, jane, admin)
+	assert.NoError(t, err)
+
+	assert.ErrorContains(t, mutation.SetAuthorRole(ctx, budi, authors.RoleEditor, jane), "FORBIDDEN")
+	assert.ErrorContains(t, mutation.SetAuthorRole(ctx, budi, authors.Role("owner"), admin), "INVALID_INPUT")
+	assert.ErrorContains(t, mutation.SetAuthorRole(ctx, admin, authors.RoleAuthor, admin), "FORBIDDEN")
+	assert.ErrorContains(t, mutation.SetAuthorRole(ctx, uuid.New(), authors.RoleEditor, admin), "NOT_FOUND")
+
+	assert.NoError(t, mutation.SetAuthorRole(ctx, budi, authors.RoleEditor, admin))
+	author, err := mutation.GetAuthorByID(ctx, budi)
+	assert.NoError(t, err)
+	assert.Equal(t, authors.RoleEditor, author.Role)
+
+	// editors edit articles, not accounts
+	_, err = mutation.UpdateAuthor(ctx, &authors.AuthorInput{Name: "Hacked", Email: "evil@example.com"}, jane, budi)
+	assert.ErrorContains(t, err, "FORBIDDEN")
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	cleanDB()
+	mutation := newMutation()
+	denylist := authors.NewTokenDenylist(authors.NewAuthorRepo(testDB, testDB))
+
+	_, err := mutation.CreateAuthor(ctx, &authors.AuthorInput{Name: "Rani", Email: "rani@example.com", Password: "rahasia123"})
+	assert.NoError(t, err)
+
+	first, err := mutation.LoginAuthor(ctx, "rani@example.com", "rahasia123", "10.0.0.1")
+	assert.NoError(t, err)
+	assert.Equal(t, int(authors.AccessTokenTTL.Seconds()), first.ExpiresIn)
+
+	second, err := mutation.RefreshToken(ctx, first.RefreshToken)
+	assert.NoError(t, err)
+	assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+
+	// reusing a rotated token revokes the whole family
+	_, err = mutation.RefreshToken(ctx, first.RefreshToken)
+	assert.ErrorContains(t, err, "UNAUTHORIZED")
+	_, err = mutation.RefreshToken(ctx, second.RefreshToken)
+	assert.ErrorContains(t, err, "UNAUTHORIZED")
+	revoked, err := denylist.IsRevoked(ctx, tokenID(t, second.AccessToken))
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	_, err = mutation.RefreshToken(ctx, "not-a-token")
+	assert.ErrorContains(t, err, "UNAUTHORIZED")
+}
+
+func TestLogout(t *testing.T) {
+	cleanDB()
+	mutation := newMutation()
+	denylist := authors.NewTokenDenylist(authors.NewAuthorRepo(testDB, testDB))
+
+	id, err := mutation.CreateAuthor(ctx, &authors.AuthorInput{Name: "Rani", Email: "rani@example.com", Password: "rahasia123"})
+	assert.NoError(t, err)
+	pair, err := mutation.LoginAuthor(ctx, "rani@example.com", "rahasia123", "10.0.0.1")
+	assert.NoError(t, err)
+	other, err := mutation.LoginAuthor(ctx, "rani@example.com", "rahasia123", "10.0.0.1")
+	assert.NoError(t, err)
+
+	jti := tokenID(t, pair.AccessToken)
+	revoked, err := denylist.IsRevoked(ctx, jti)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	err = mutation.Logout(ctx, *id, uuid.MustParse(jti), time.Now().Add(authors.AccessTokenTTL))
+	assert.NoError(t, err)
+
+	revoked, err = denylist.IsRevoked(ctx, jti)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+	_, err = mutation.RefreshToken(ctx, pair.RefreshToken)
+	assert.ErrorContains(t, err, "UNAUTHORIZED")
+
+	// other logins of the same author keep working
+	_, err = mutation.RefreshToken(ctx, other.RefreshToken)
+	assert.NoError(t, err)
+}
+
+func tokenID(t *testing.T, accessToken string) string {
+	claims := &middleware.Claims{}
+	_, _, err := jwt.NewParser().ParseUnverified(accessToken, claims)
+	assert.NoError(t, err)
+	return claims.ID
+}
+
+func TestLoginThrottling(t *testing.T) {
+	cleanDB()
+	testDB.Exec("DELETE FROM login_audit")
+	mutation := newMutation()
+
+	_, err := mutation.CreateAuthor(ctx, &authors.AuthorInput{Name: "Rani", Email: "rani@example.com", Password: "rahasia123"})
+	assert.NoError(t, err)
+
+	// an unknown email and a wrong password look the same
+	_, unknown := mutation.LoginAuthor(ctx, "nobody@example.com", "rahasia123", "10.0.0.1")
+	_, wrong := mutation.LoginAuthor(ctx, "rani@example.com", "salah", "10.0.0.1")
+	assert.ErrorContains(t, unknown, "Invalid email or password")
+	assert.Equal(t, unknown.Error(), wrong.Error())
+
+	for i := 0; i < authors.DefaultEmailPolicy.FreeAttempts; i++ {
+		_, err = mutation.LoginAuthor(ctx, "rani@example.com", "salah", "10.0.0.1")
+		assert.ErrorContains(t, err, "UNAUTHORIZED")
+	}
+	// even the right password waits once the delay has started
+	_, err = mutation.LoginAuthor(ctx, "rani@example.com", "rahasia123", "10.0.0.2")
+	assert.ErrorContains(t, err, "TOO_MANY_REQUESTS")
+	assert.ErrorContains(t, err, "retry_after")
+
+	// other accounts are not affected
+	_, err = mutation.LoginAuthor(ctx, "nobody@example.com", "rahasia123", "10.0.0.2")
+	assert.ErrorContains(t, err, "UNAUTHORIZED")
+
+	var reasons []string
+	err = testDB.Select(&reasons, "SELECT reason FROM login_audit WHERE email = $1 ORDER BY created_at", "rani@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		authors.LoginFailureWrongPassword,
+		authors.LoginFailureWrongPassword,
+		authors.LoginFailureWrongPassword,
+		authors.LoginFailureWrongPassword,
+		authors.LoginFailureThrottled,
+	}, reasons)
+}
+
+func TestLoginThrottlingParallel(t *testing.T) {
+	cleanDB()
+	mutation := newMutation()
+
+	_, err := mutation.CreateAuthor(ctx, &authors.AuthorInput{Name: "Rani", Email: "rani@example.com", Password: "rahasia123"})
+	assert.NoError(t, err)
+
+	// guesses sent at once still get only the free attempts between them
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := mutation.LoginAuthor(ctx, "rani@example.com", "salah", fmt.Sprintf("10.0.1.%d", i))
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	checked := 0
+	for err := range errs {
+		if !strings.Contains(err.Error(), "TOO_MANY_REQUESTS") {
+			checked++
+		}
+	}
+	assert.Equal(t, authors.DefaultEmailPolicy.FreeAttempts+1, checked)
+}
+
+func TestLoginAttemptStores(t *testing.T) {
+	testDB.Exec("DELETE FROM login_attempts")
+	stores := map[string]authors.LoginAttemptStore{
+		"memory":   authors.NewMemoryLoginAttemptStore(time.Hour),
+		"postgres": authors.NewPostgresLoginAttemptStore(testDB, time.Hour),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			key := "email:" + name + "@example.com"
+			now := time.Now()
+
+			// a failure older than the window starts a new count
+			_, err := store.Attempt(ctx, key, now.Add(-2*time.Hour))
+			assert.NoError(t, err)
+			attempts, err := store.Get(ctx, key)
+			assert.NoError(t, err)
+			assert.Equal(t, 0, attempts.Failures)
+
+			// Attempt returns the count from before the attempt
+			_, err = store.Attempt(ctx, key, now)
+			assert.NoError(t, err)
+			attempts, err = store.Attempt(ctx, key, now)
+			assert.NoError(t, err)
+			assert.Equal(t, 1, attempts.Failures)
+
+			assert.NoError(t, store.Release(ctx, key))
+			attempts, err = store.Get(ctx, key)
+			assert.NoError(t, err)
+			assert.Equal(t, 1, attempts.Failures)
+
+			assert.NoError(t, store.Reset(ctx, key))
+			attempts, err = store.Get(ctx, key)
+			assert.NoError(t, err)
+			assert.Equal(t, 0, attempts.Failures)
+		})
+	}
+}
+
+var linkTokenPattern = regexp.MustCompile(`token=([A-Za-z0-9_.-]+)`)
+
+func TestPasswordChangeAndReset(t *testing.T) {
+	cleanDB()
+	mutation := newMutation()
+	denylist := authors.NewTokenDenylist(authors.NewAuthorRepo(testDB, testDB))
+
+	id, err := mutation.CreateAuthor(ctx, &authors.AuthorInput{Name: "Rani", Email: "rani@example.com", Password: "rahasia123"})
+	assert.NoError(t, err)
+	before, err := mutation.LoginAuthor(ctx, "rani@example.com", "rahasia123", "10.0.0.1")
+	assert.NoError(t, err)
+
+	_, err = mutation.ChangePassword(ctx, *id, "salah", "rahasia456", "10.0.0.1")
+	assert.ErrorContains(t, err, "FORBIDDEN")
+	_, err = mutation.ChangePassword(ctx, *id, "rahasia123", "pendek", "10.0.0.1")
+	assert.ErrorContains(t, err, "INVALID_INPUT")
+
+	// changing the password ends every other session
+	changed, err := mutation.ChangePassword(ctx, *id, "rahasia123", "rahasia456", "10.0.0.1")
+	assert.NoError(t, err)
+	_, err = mutation.RefreshToken(ctx, before.RefreshToken)
+	assert.ErrorContains(t, err, "UNAUTHORIZED")
+	revoked, err := denylist.IsRevoked(ctx, tokenID(t, before.AccessToken))
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+	_, err = mutation.LoginAuthor(ctx, "rani@example.com", "rahasia456", "10.0.0.1")
+	assert.NoError(t, err)
+
+	// unknown emails get the same answer and no mail
+	sent.mails = nil
+	assert.NoError(t, mutation.ForgotPassword(ctx, "nobody@example.com"))
+	assert.Empty(t, sent.mails)
+
+	assert.NoError(t, mutation.ForgotPassword(ctx, "rani@example.com"))
+	assert.NoError(t, mutation.ForgotPassword(ctx, "rani@example.com"))
+	assert.Len(t, sent.mails, 1)
+	assert.Equal(t, "rani@example.com", sent.mails[0].To)
+	match := linkTokenPattern.FindStringSubmatch(sent.mails[0].Body)
+	assert.Len(t, match, 2)
+	token := match[1]
+
+	err = mutation.ResetPassword(ctx, "not-a-token", "rahasia789")
+	assert.ErrorContains(t, err, "Invalid or expired reset token")
+	assert.NoError(t, mutation.ResetPassword(ctx, token, "rahasia789"))
+	err = mutation.ResetPassword(ctx, token, "rahasia000")
+	assert.ErrorContains(t, err, "Invalid or expired reset token")
+
+	_, err = mutation.RefreshToken(ctx, changed.RefreshToken)
+	assert.ErrorContains(t, err, "UNAUTHORIZED")
+	_, err = mutation.LoginAuthor(ctx, "rani@example.com", "rahasia456", "10.0.0.1")
+	assert.ErrorContains(t, err, "UNAUTHORIZED")
+	_, err = mutation.LoginAuthor(ctx, "rani@example.com", "rahasia789", "10.0.0.1")
+	assert.NoError(t, err)
+}
+
+// sentToken returns the token of the last link mailed to email.
+func sentToken(t *testing.T, email string) string {
+	for i := len(sent.mails) - 1; i >= 0; i-- {
+		if sent.mails[i].To != email {
+			continue
+		}
+		match := linkTokenPattern.FindStringSubmatch(sent.mails[i].Body)
+		if assert.Len(t, match, 2) {
+			return match[1]
+		}
+	}
+	t.Fatalf("no mail sent to %s", email)
+	return ""
+}
+
+func TestEmailVerification(t *testing.T) {
+	cleanDB()
+	sent.mails = nil
+	mutation := newMutation()
+
+	_, err := mutation.CreateAuthor(ctx, &authors.AuthorInput{Name: "Rani", Email: "not an email", Password: "rahasia123"})
+	assert.ErrorContains(t, err, "INVALID_INPUT")
+
+	id, err := mutation.CreateAuthor(ctx, &authors.AuthorInput{Name: "Rani", Email: "rani@example.com", Password: "rahasia123"})
+	assert.NoError(t, err)
+	author, err := mutation.GetAuthorByID(ctx, *id)
+	assert.NoError(t, err)
+	assert.False(t, author.EmailVerified())
+
+	// unverified authors can log in
+	_, err = mutation.LoginAuthor(ctx, "rani@example.com", "rahasia123", "10.0.0.1")
+	assert.NoError(t, err)
+
+	// the signup mail was just sent
+	err = mutation.ResendVerification(ctx, *id)
+	assert.ErrorContains(t, err, "TOO_MANY_REQUESTS")
+
+	token := sentToken(t, "rani@example.com")
+	assert.ErrorContains(t, mutation.VerifyEmail(ctx, token+"x"), "Invalid or expired verification link")
+	assert.NoError(t, mutation.VerifyEmail(ctx, token))
+	assert.NoError(t, mutation.VerifyEmail(ctx, token))
+	author, err = mutation.GetAuthorByID(ctx, *id)
+	assert.NoError(t, err)
+	assert.True(t, author.EmailVerified())
+	assert.ErrorContains(t, mutation.ResendVerification(ctx, *id), "Email is already verified")
+
+	// a new email waits for verification
+	_, err = mutation.UpdateAuthor(ctx, &authors.AuthorInput{Name: "Rani", Email: "rani@example.org"}, *id, *id)
+	assert.NoError(t, err)
+	author, err = mutation.GetAuthorByID(ctx, *id)
+	assert.NoError(t, err)
+	assert.Equal(t, "rani@example.com", author.Email)
+	assert.Equal(t, "rani@example.org", *author.PendingEmail)
+
+	changeToken := sentToken(t, "rani@example.org")
+	assert.NoError(t, mutation.VerifyEmail(ctx, changeToken))
+	author, err = mutation.GetAuthorByID(ctx, *id)
+	assert.NoError(t, err)
+	assert.Equal(t, "rani@example.org", author.Email)
+	assert.Nil(t, author.PendingEmail)
+
+	// the link for the old address no longer applies
+	assert.ErrorContains(t, mutation.VerifyEmail(ctx, token), "Invalid or expired verification link")
+
+	// links are signed
+	other, err := authors.NewEmailVerifier([]byte(strings.Repeat("x", 32)))
+	assert.NoError(t, err)
+	forged, err := other.Sign(*id, "evil@example.com", time.Now())
+	assert.NoError(t, err)
+	assert.ErrorContains(t, mutation.VerifyEmail(ctx, forged), "Invalid or expired verification link")
+	expired, err := newEmailVerifier().Sign(*id, "rani@example.org", time.Now().Add(-authors.EmailVerificationTTL-time.Minute))
+	assert.NoError(t, err)
+	assert.ErrorContains(t, mutation.VerifyEmail(ctx, expired), "Invalid or expired verification link")
+
+	// an address waiting for another author's verification is taken
+	budi, err := mutation.CreateAuthor(ctx, &authors.AuthorInput{Name: "Budi", Email: "budi@example.com", Password: "rahasia123"})
+	assert.NoError(t, err)
+	_, err = mutation.UpdateAuthor(ctx, &authors.AuthorInput{Name: "Budi", Email: "shared@example.com"}, *budi, *budi)
+	assert.NoError(t, err)
+	_, err = mutation.UpdateAuthor(ctx, &authors.AuthorInput{Name: "Rani", Email: "shared@example.com"}, *id, *id)
+	assert.ErrorContains(t, err, "Email is already in use")
+
+	// and a pending address taken in the meantime is a conflict
+	budiToken := sentToken(t, "shared@example.com")
+	_, err = mutation.CreateAuthor(ctx, &authors.AuthorInput{Name: "Sari", Email: "shared@example.com", Password: "rahasia123"})
+	assert.NoError(t, err)
+	err = mutation.VerifyEmail(ctx, budiToken)
+	assert.ErrorContains(t, err, "Email is already in use")
+}
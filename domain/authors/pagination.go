@@ -0,0 +1,115 @@
+package authors
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultPageLimit = 10
+	MaxPageLimit     = 100
+)
+
+// AuthorSort is the order of an author listing; a leading "-" sorts
+// descending. Ties are broken by id, so every author has a unique position
+// to continue from.
+type AuthorSort string
+
+const (
+	SortByName          AuthorSort = "name"
+	SortByNameDesc      AuthorSort = "-name"
+	SortByCreatedAt     AuthorSort = "created_at"
+	SortByCreatedAtDesc AuthorSort = "-created_at"
+)
+
+// PageRequest describes one page of a keyset paginated author listing.
+// Cursor is the opaque NextCursor of the previous page, empty for the first
+// page. Name, when set, only keeps authors whose name contains it, ignoring
+// case.
+type PageRequest struct {
+	Name   string
+	Sort   AuthorSort
+	Limit  int
+	Cursor string
+}
+
+type AuthorPage struct {
+	Authors    []*Author
+	NextCursor string
+}
+
+// AuthorPageResponse is an AuthorPage as returned by the API.
+type AuthorPageResponse struct {
+	Authors    []*AuthorPublic `json:"authors"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+// authorCursor is the position of the last author of a page: the value of
+// the sort column and the id.
+type authorCursor struct {
+	Name      string    `json:"n,omitempty"`
+	CreatedAt time.Time `json:"c,omitempty"`
+	ID        uuid.UUID `json:"id"`
+}
+
+func (p PageRequest) size() int {
+	if p.Limit <= 0 {
+		return DefaultPageLimit
+	}
+	if p.Limit > MaxPageLimit {
+		return MaxPageLimit
+	}
+	return p.Limit
+}
+
+func (p PageRequest) sort() AuthorSort {
+	if p.Sort == "" {
+		return SortByName
+	}
+	return p.Sort
+}
+
+func (p PageRequest) after() (*authorCursor, error) {
+	if p.Cursor == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(p.Cursor)
+	if err != nil {
+		return nil, ErrInvalidInput.WithDetails(map[string]interface{}{
+			"cursor": p.Cursor,
+		})
+	}
+	var cursor authorCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil || cursor.ID == uuid.Nil {
+		return nil, ErrInvalidInput.WithDetails(map[string]interface{}{
+			"cursor": p.Cursor,
+		})
+	}
+	return &cursor, nil
+}
+
+func encodeCursor(author *Author) string {
+	raw, err := json.Marshal(authorCursor{
+		Name:      author.Name,
+		CreatedAt: author.CreatedAt,
+		ID:        author.ID,
+	})
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func NewAuthorPageResponse(page *AuthorPage) *AuthorPageResponse {
+	authors := make([]*AuthorPublic, 0, len(page.Authors))
+	for _, author := range page.Authors {
+		authors = append(authors, NewAuthorPublic(author))
+	}
+	return &AuthorPageResponse{
+		Authors:    authors,
+		NextCursor: page.NextCursor,
+	}
+}
@@ -0,0 +1,55 @@
+package authors
+
+import (
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	MinPasswordLength = 8
+	// MaxPasswordLength is in bytes: bcrypt ignores everything after 72.
+	MaxPasswordLength = 72
+
+	PasswordResetTTL = time.Hour
+	// PasswordResetInterval is the least time between two reset emails to the
+	// same author.
+	PasswordResetInterval = time.Minute
+)
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// PasswordResetToken is a stored reset token. Like refresh tokens only the
+// SHA-256 of the token is kept; Email is filled in when it is looked up.
+type PasswordResetToken struct {
+	ID        uuid.UUID  `db:"id"`
+	AuthorID  uuid.UUID  `db:"author_id"`
+	TokenHash string     `db:"token_hash"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	UsedAt    *time.Time `db:"used_at"`
+	CreatedAt time.Time  `db:"created_at"`
+	Email     string     `db:"email"`
+}
+
+func validatePassword(password string) error {
+	if utf8.RuneCountInString(password) < MinPasswordLength || len(password) > MaxPasswordLength {
+		return ErrInvalidInput.WithDetails(map[string]interface{}{
+			"password": fmt.Sprintf("must be %d to %d bytes long", MinPasswordLength, MaxPasswordLength),
+		})
+	}
+	return nil
+}
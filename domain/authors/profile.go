@@ -0,0 +1,105 @@
+package authors
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	MaxBioLength     = 2000
+	MaxSocialLinks   = 10
+	maxSocialNetwork = 32
+)
+
+// EventAuthorSync is the outbox event written when an author changes. The
+// relay copies the author again into every article document of that author.
+const EventAuthorSync = "author.sync"
+
+// SocialLinks maps a network name, such as "github" or "x", to a profile
+// URL. It is stored as JSONB.
+type SocialLinks map[string]string
+
+func (s SocialLinks) Value() (driver.Value, error) {
+	if s == nil {
+		return "{}", nil
+	}
+	raw, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return string(raw), nil
+}
+
+func (s *SocialLinks) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*s = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, s)
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	}
+	return fmt.Errorf("cannot scan %T into SocialLinks", src)
+}
+
+// Slugify turns a name into the base of a URL slug: lower case letters and
+// digits separated by single dashes. A name without any of them gives
+// "author".
+func Slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	if b.Len() == 0 {
+		return "author"
+	}
+	return b.String()
+}
+
+// validateProfile checks the optional profile fields of u. URLs must be
+// absolute http or https URLs.
+func validateProfile(u *AuthorInput) error {
+	invalid := func(field string, value interface{}) error {
+		return ErrInvalidInput.WithDetails(map[string]interface{}{
+			field: value,
+		})
+	}
+	if utf8.RuneCountInString(u.Bio) > MaxBioLength {
+		return invalid("bio", fmt.Sprintf("longer than %d characters", MaxBioLength))
+	}
+	if u.AvatarURL != "" && !isWebURL(u.AvatarURL) {
+		return invalid("avatar_url", u.AvatarURL)
+	}
+	if u.Website != "" && !isWebURL(u.Website) {
+		return invalid("website", u.Website)
+	}
+	if len(u.SocialLinks) > MaxSocialLinks {
+		return invalid("social_links", fmt.Sprintf("more than %d links", MaxSocialLinks))
+	}
+	for network, link := range u.SocialLinks {
+		if network == "" || len(network) > maxSocialNetwork || !isWebURL(link) {
+			return invalid("social_links", map[string]string{network: link})
+		}
+	}
+	return nil
+}
+
+func isWebURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
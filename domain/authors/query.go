@@ -1,28 +1,255 @@
 package authors
 
 const CreateAuthorQuery = `
-	INSERT INTO authors (id, name, email, password)
-	VALUES (:id, :name, :email, :password)
+	INSERT INTO authors (id, name, legal_name, slug, bio, avatar_url, website, social_links, email, password, role, verification_sent_at)
+	VALUES (:id, :name, :legal_name, :slug, :bio, :avatar_url, :website, :social_links, :email, :password, :role, :verification_sent_at)
 `
 
+// UpdateAuthorQuery leaves the slug alone, so links to the author page keep
+// working after a rename. The email only changes through verification.
 const UpdateAuthorQuery = `
 	UPDATE authors
-	SET name = :name, email = :email, updated_at = :updated_at
+	SET name = :name, legal_name = :legal_name, bio = :bio, avatar_url = :avatar_url,
+		website = :website, social_links = :social_links, updated_at = :updated_at
 	WHERE id = :id
 `
 
+const UpdateAuthorRoleQuery = `
+	UPDATE authors SET role = $2, updated_at = NOW() WHERE id = $1
+`
+
 const FindAuthorByIDQuery = `
-	SELECT id, name, email FROM authors WHERE id = $1
+	SELECT id, name, legal_name, slug, bio, avatar_url, website, social_links, email, role,
+		email_verified_at, pending_email, created_at, updated_at
+	FROM authors WHERE id = $1
+`
+
+const FindAuthorBySlugQuery = `
+	SELECT id, name, legal_name, slug, bio, avatar_url, website, social_links, email, role,
+		email_verified_at, pending_email, created_at, updated_at
+	FROM authors WHERE slug = $1
 `
 
 const FindAuthorByIDListQuery = `
-	SELECT id, name, email FROM authors WHERE id IN (?)
+	SELECT id, name, slug, avatar_url, email, created_at, updated_at FROM authors WHERE id IN (?)
+`
+
+// FindSlugsQuery returns $1 and its numbered variants that are taken.
+const FindSlugsQuery = `
+	SELECT slug FROM authors WHERE slug = $1 OR slug LIKE $1 || '-%'
+`
+
+// The insert of a new author runs under a savepoint, so that losing a slug to
+// a concurrent create can be rolled back and retried inside the transaction.
+const (
+	SavepointCreateAuthorQuery           = `SAVEPOINT create_author`
+	RollbackToSavepointCreateAuthorQuery = `ROLLBACK TO SAVEPOINT create_author`
+	ReleaseSavepointCreateAuthorQuery    = `RELEASE SAVEPOINT create_author`
+)
+
+const EnqueueAuthorSyncQuery = `
+	INSERT INTO outbox (id, aggregate_id, event_type, payload)
+	VALUES ($1, $2, $3, '{}'::jsonb)
 `
 
+// GetIDAuthorsByNameQuery matches names containing $1, the LIKE-escaped
+// input, ignoring case, ordered by name. At most $2 authors are returned.
 const GetIDAuthorsByNameQuery = `
-	SELECT id, name FROM authors WHERE name = $1
+	SELECT id, name FROM authors WHERE name ILIKE '%' || $1 || '%'
+	ORDER BY name, id
+	LIMIT $2
 `
 
 const FindAuthorByEmailQuery = `
-	SELECT id, name, email, password FROM authors WHERE email = $1
+	SELECT id, name, email, password, role FROM authors WHERE email = $1
+`
+
+// SuggestAuthorsByNameQuery matches names containing $2, the LIKE-escaped
+// input, using the trigram index. Names starting with it score highest.
+const SuggestAuthorsByNameQuery = `
+	SELECT id, name,
+		(CASE WHEN name ILIKE $2 || '%' THEN 1 ELSE 0 END + similarity(name, $1)) / 2 AS score
+	FROM authors
+	WHERE name ILIKE '%' || $2 || '%'
+	ORDER BY score DESC, name
+	LIMIT $3
+`
+
+// FindAuthorsPageQuery lists one page of authors in keyset order, optionally
+// only those whose name contains $1. It is completed with fmt.Sprintf from
+// authorSortClauses, never from user input: the sort column, the keyset
+// comparison and the direction.
+const FindAuthorsPageQuery = `
+	SELECT id, name, slug, bio, avatar_url, created_at, updated_at FROM authors
+	WHERE ($1 = '' OR name ILIKE '%%' || $1 || '%%')
+	AND ($2::boolean OR (%[1]s, id) %[2]s ($3, $4))
+	ORDER BY %[1]s %[3]s, id %[3]s
+	LIMIT $5
+`
+
+const CreateRefreshTokenQuery = `
+	INSERT INTO refresh_tokens (id, author_id, family_id, token_hash, access_token_id, access_expires_at, expires_at)
+	VALUES (:id, :author_id, :family_id, :token_hash, :access_token_id, :access_expires_at, :expires_at)
+`
+
+const FindRefreshTokenForUpdateQuery = `
+	SELECT id, author_id, family_id, token_hash, access_token_id, access_expires_at, expires_at, used_at, revoked_at, created_at
+	FROM refresh_tokens WHERE token_hash = $1
+	FOR UPDATE
+`
+
+const MarkRefreshTokenUsedQuery = `
+	UPDATE refresh_tokens SET used_at = NOW() WHERE id = $1
+`
+
+// RevokeTokenFamilyQuery revokes every refresh token of family $1 and puts
+// the access tokens issued with them that have not expired yet on the
+// denylist.
+const RevokeTokenFamilyQuery = `
+	WITH revoked AS (
+		UPDATE refresh_tokens SET revoked_at = NOW()
+		WHERE family_id = $1 AND revoked_at IS NULL
+		RETURNING access_token_id, access_expires_at
+	)
+	INSERT INTO revoked_tokens (id, expires_at)
+	SELECT access_token_id, access_expires_at FROM revoked WHERE access_expires_at > NOW()
+	ON CONFLICT (id) DO NOTHING
+`
+
+const FindTokenFamilyQuery = `
+	SELECT family_id FROM refresh_tokens WHERE author_id = $1 AND access_token_id = $2
+`
+
+const RevokeAccessTokenQuery = `
+	INSERT INTO revoked_tokens (id, expires_at) VALUES ($1, $2)
+	ON CONFLICT (id) DO NOTHING
+`
+
+const DeleteExpiredRevokedTokensQuery = `
+	DELETE FROM revoked_tokens WHERE expires_at < NOW()
+`
+
+const IsTokenRevokedQuery = `
+	SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE id = $1)
+`
+
+const FindLoginAttemptsQuery = `
+	SELECT failures, last_failure_at FROM login_attempts
+	WHERE key = $1 AND last_failure_at > $2
+`
+
+// CreateLoginAttemptsQuery makes sure key $1 has a row to lock, so that even
+// the first attempts on a key wait for one another.
+const CreateLoginAttemptsQuery = `
+	INSERT INTO login_attempts (key, failures, last_failure_at) VALUES ($1, 0, $2)
+	ON CONFLICT (key) DO NOTHING
+`
+
+const FindLoginAttemptsForUpdateQuery = `
+	SELECT failures, last_failure_at FROM login_attempts
+	WHERE key = $1
+	FOR UPDATE
+`
+
+// FailLoginAttemptQuery counts a failure at $2 for key $1, starting over when
+// the previous one is older than $3.
+const FailLoginAttemptQuery = `
+	INSERT INTO login_attempts (key, failures, last_failure_at) VALUES ($1, 1, $2)
+	ON CONFLICT (key) DO UPDATE SET
+		failures = CASE WHEN login_attempts.last_failure_at > $3 THEN login_attempts.failures + 1 ELSE 1 END,
+		last_failure_at = EXCLUDED.last_failure_at
+	RETURNING failures, last_failure_at
+`
+
+const ReleaseLoginAttemptQuery = `
+	UPDATE login_attempts SET failures = failures - 1
+	WHERE key = $1 AND failures > 0
+`
+
+const DeleteLoginAttemptsQuery = `
+	DELETE FROM login_attempts WHERE key = $1
+`
+
+const CreateLoginAuditQuery = `
+	INSERT INTO login_audit (id, email, ip, reason, created_at)
+	VALUES (:id, :email, :ip, :reason, :created_at)
+`
+
+const FindAuthorPasswordQuery = `
+	SELECT password FROM authors WHERE id = $1
+`
+
+const FindAuthorRoleQuery = `
+	SELECT role FROM authors WHERE id = $1
+`
+
+const UpdateAuthorPasswordQuery = `
+	UPDATE authors SET password = $2, updated_at = NOW() WHERE id = $1
+`
+
+// RevokeAuthorTokensQuery is RevokeTokenFamilyQuery for every family of
+// author $1.
+const RevokeAuthorTokensQuery = `
+	WITH revoked AS (
+		UPDATE refresh_tokens SET revoked_at = NOW()
+		WHERE author_id = $1 AND revoked_at IS NULL
+		RETURNING access_token_id, access_expires_at
+	)
+	INSERT INTO revoked_tokens (id, expires_at)
+	SELECT access_token_id, access_expires_at FROM revoked WHERE access_expires_at > NOW()
+	ON CONFLICT (id) DO NOTHING
+`
+
+const CreatePasswordResetTokenQuery = `
+	INSERT INTO password_reset_tokens (id, author_id, token_hash, expires_at, created_at)
+	VALUES (:id, :author_id, :token_hash, :expires_at, :created_at)
+`
+
+const FindPasswordResetTokenForUpdateQuery = `
+	SELECT t.id, t.author_id, t.token_hash, t.expires_at, t.used_at, t.created_at, a.email
+	FROM password_reset_tokens t JOIN authors a ON a.id = t.author_id
+	WHERE t.token_hash = $1
+	FOR UPDATE OF t
+`
+
+const HasRecentPasswordResetQuery = `
+	SELECT EXISTS (SELECT 1 FROM password_reset_tokens WHERE author_id = $1 AND created_at > $2)
+`
+
+// UsePasswordResetTokensQuery marks every unused reset token of author $1 as
+// used, so only the newest link works and none works after a reset.
+const UsePasswordResetTokensQuery = `
+	UPDATE password_reset_tokens SET used_at = NOW() WHERE author_id = $1 AND used_at IS NULL
+`
+
+const FindAuthorForUpdateQuery = `
+	SELECT id, name, email, role, email_verified_at, pending_email, verification_sent_at
+	FROM authors WHERE id = $1
+	FOR UPDATE
+`
+
+// EmailInUseQuery tells whether an author other than $2 has $1 as their
+// email or is waiting to verify it.
+const EmailInUseQuery = `
+	SELECT EXISTS (
+		SELECT 1 FROM authors
+		WHERE id <> $2 AND (email = $1 OR pending_email = $1)
+	)
+`
+
+const SetPendingEmailQuery = `
+	UPDATE authors SET pending_email = $2, updated_at = NOW() WHERE id = $1
+`
+
+const MarkVerificationSentQuery = `
+	UPDATE authors SET verification_sent_at = $2 WHERE id = $1
+`
+
+// VerifyEmailQuery makes $2 the verified email of author $1. The pending
+// change is done when $2 is the pending email and kept otherwise.
+const VerifyEmailQuery = `
+	UPDATE authors
+	SET email = $2, email_verified_at = NOW(), updated_at = NOW(),
+		pending_email = CASE WHEN pending_email = $2 THEN NULL ELSE pending_email END
+	WHERE id = $1
 `
\ No newline at end of file
@@ -2,15 +2,43 @@ package authors
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 )
 
 type AuthorRepository interface {
-	Save(ctx context.Context, u *AuthorInput) (*uuid.UUID, error)
-	Update(ctx context.Context, u *AuthorInput, id uuid.UUID) (*uuid.UUID, error)
+	Save(ctx context.Context, u *AuthorInput, tx *sqlx.Tx) (*uuid.UUID, error)
+	Update(ctx context.Context, u *AuthorInput, id uuid.UUID, tx *sqlx.Tx) (*uuid.UUID, error)
+	UpdateRole(ctx context.Context, id uuid.UUID, role Role) error
+	EnqueueSync(ctx context.Context, id uuid.UUID, tx *sqlx.Tx) error
+	SaveRefreshToken(ctx context.Context, t *RefreshToken, tx *sqlx.Tx) error
+	FindRefreshTokenForUpdate(ctx context.Context, hash string, tx *sqlx.Tx) (*RefreshToken, error)
+	MarkRefreshTokenUsed(ctx context.Context, id uuid.UUID, tx *sqlx.Tx) error
+	RevokeTokenFamily(ctx context.Context, familyID uuid.UUID, tx *sqlx.Tx) error
+	FindTokenFamily(ctx context.Context, authorID uuid.UUID, accessTokenID uuid.UUID, tx *sqlx.Tx) (uuid.UUID, error)
+	RevokeAccessToken(ctx context.Context, id uuid.UUID, expiresAt time.Time, tx *sqlx.Tx) error
+	IsTokenRevoked(ctx context.Context, id uuid.UUID) (bool, error)
+	SaveLoginAudit(ctx context.Context, audit *LoginAudit) error
+	FindPassword(ctx context.Context, id uuid.UUID) (string, error)
+	FindRole(ctx context.Context, id uuid.UUID) (Role, error)
+	UpdatePassword(ctx context.Context, id uuid.UUID, hash string, tx *sqlx.Tx) error
+	RevokeAuthorTokens(ctx context.Context, authorID uuid.UUID, tx *sqlx.Tx) error
+	SavePasswordResetToken(ctx context.Context, t *PasswordResetToken, tx *sqlx.Tx) error
+	FindPasswordResetTokenForUpdate(ctx context.Context, hash string, tx *sqlx.Tx) (*PasswordResetToken, error)
+	HasRecentPasswordReset(ctx context.Context, authorID uuid.UUID, since time.Time, tx *sqlx.Tx) (bool, error)
+	UsePasswordResetTokens(ctx context.Context, authorID uuid.UUID, tx *sqlx.Tx) error
+	FindForUpdate(ctx context.Context, id uuid.UUID, tx *sqlx.Tx) (*Author, error)
+	EmailInUse(ctx context.Context, email string, exceptID uuid.UUID, tx *sqlx.Tx) (bool, error)
+	SetPendingEmail(ctx context.Context, id uuid.UUID, email *string, tx *sqlx.Tx) error
+	MarkVerificationSent(ctx context.Context, id uuid.UUID, at time.Time, tx *sqlx.Tx) error
+	VerifyEmail(ctx context.Context, id uuid.UUID, email string, tx *sqlx.Tx) error
 	FindByID(ctx context.Context, id uuid.UUID) (*Author, error)
+	FindBySlug(ctx context.Context, slug string) (*Author, error)
 	FindByIDList(ctx context.Context, idList []uuid.UUID) ([]Author, error)
 	FindIDNameByName(ctx context.Context, name string) ([]*AuthorIDName, error)
 	FindByEmail(ctx context.Context, email string) (*Author, error)
+	SuggestByName(ctx context.Context, prefix string, limit int) ([]*AuthorSuggestion, error)
+	FindPage(ctx context.Context, page PageRequest) (*AuthorPage, error)
 }
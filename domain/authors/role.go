@@ -0,0 +1,31 @@
+package authors
+
+// Role is what an author may do besides managing their own account and
+// articles. Every author starts as RoleAuthor.
+type Role string
+
+const (
+	RoleAuthor Role = "author"
+	RoleEditor Role = "editor"
+	RoleAdmin  Role = "admin"
+)
+
+func (r Role) Valid() bool {
+	switch r {
+	case RoleAuthor, RoleEditor, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+// CanEditAnyArticle reports whether r may change the articles of other
+// authors.
+func (r Role) CanEditAnyArticle() bool {
+	return r == RoleEditor || r == RoleAdmin
+}
+
+// CanManageAuthors reports whether r may change the accounts and roles of
+// other authors.
+func (r Role) CanManageAuthors() bool {
+	return r == RoleAdmin
+}
@@ -0,0 +1,79 @@
+package authors
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// TokenPair is what a login or a refresh returns. The access token is a JWT
+// sent as a Bearer token; the refresh token is opaque and can be exchanged
+// once for a new pair.
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// RefreshToken is a stored refresh token. Only the SHA-256 of the token is
+// kept. All tokens rotated from one login share a FamilyID.
+type RefreshToken struct {
+	ID              uuid.UUID  `db:"id"`
+	AuthorID        uuid.UUID  `db:"author_id"`
+	FamilyID        uuid.UUID  `db:"family_id"`
+	TokenHash       string     `db:"token_hash"`
+	AccessTokenID   uuid.UUID  `db:"access_token_id"`
+	AccessExpiresAt time.Time  `db:"access_expires_at"`
+	ExpiresAt       time.Time  `db:"expires_at"`
+	UsedAt          *time.Time `db:"used_at"`
+	RevokedAt       *time.Time `db:"revoked_at"`
+	CreatedAt       time.Time  `db:"created_at"`
+}
+
+// newRefreshToken returns a random opaque token and its hash. Password reset
+// tokens are made the same way.
+func newRefreshToken() (string, string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// TokenDenylist is the middleware.Denylist of revoked access tokens.
+type TokenDenylist struct {
+	repo AuthorRepository
+}
+
+func NewTokenDenylist(repo AuthorRepository) *TokenDenylist {
+	return &TokenDenylist{repo: repo}
+}
+
+func (d *TokenDenylist) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	id, err := uuid.Parse(tokenID)
+	if err != nil {
+		return true, nil
+	}
+	return d.repo.IsTokenRevoked(ctx, id)
+}
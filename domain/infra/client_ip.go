@@ -0,0 +1,27 @@
+package infra
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// ClientIP returns the function that finds the address of the caller. The
+// X-Forwarded-For and X-Real-IP headers are only believed when the request
+// comes from one of trustedProxies, given as CIDRs; any other caller could
+// set them to whatever it likes, so its remote address is used.
+func ClientIP(trustedProxies []string) (app.ClientIP, error) {
+	cidrs := make([]*net.IPNet, 0, len(trustedProxies))
+	for _, proxy := range trustedProxies {
+		_, cidr, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q: %w", proxy, err)
+		}
+		cidrs = append(cidrs, cidr)
+	}
+	return app.ClientIPWithOption(app.ClientIPOptions{
+		RemoteIPHeaders: []string{"X-Forwarded-For", "X-Real-IP"},
+		TrustedCIDRs:    cidrs,
+	}), nil
+}
@@ -0,0 +1,140 @@
+package infra
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/afif-musyayyidin/hertz-boilerplate/domain/infra/logger"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/olivere/elastic/v7"
+)
+
+const (
+	CodeInvalidInput   = "INVALID_INPUT"
+	CodeUnauthorized   = "UNAUTHORIZED"
+	CodeForbidden      = "FORBIDDEN"
+	CodeNotFound       = "NOT_FOUND"
+	CodeConflict       = "CONFLICT"
+	CodeTimeout        = "TIMEOUT"
+	CodeInternalServer = "INTERNAL_SERVER_ERROR"
+
+	CodePreconditionFailed   = "PRECONDITION_FAILED"
+	CodePreconditionRequired = "PRECONDITION_REQUIRED"
+	CodeTooManyRequests      = "TOO_MANY_REQUESTS"
+)
+
+// Postgres SQLSTATE codes that are caused by the request rather than by the
+// server.
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+	pgCheckViolation      = "23514"
+	pgInvalidTextRepr     = "22P02"
+)
+
+var (
+	errInvalidInput   = New(CodeInvalidInput, "Invalid input")
+	errUnauthorized   = New(CodeUnauthorized, "Unauthorized")
+	errForbidden      = New(CodeForbidden, "Forbidden")
+	errNotFound       = New(CodeNotFound, "Not found")
+	errConflict       = New(CodeConflict, "Resource already exists")
+	errTimeout        = New(CodeTimeout, "Request timed out")
+	errInternalServer = New(CodeInternalServer, "Internal server error")
+
+	errPreconditionFailed   = New(CodePreconditionFailed, "Precondition failed")
+	errPreconditionRequired = New(CodePreconditionRequired, "Precondition required")
+	errTooManyRequests      = New(CodeTooManyRequests, "Too many requests")
+)
+
+var statusByCode = map[string]int{
+	CodeInvalidInput:   http.StatusBadRequest,
+	CodeUnauthorized:   http.StatusUnauthorized,
+	CodeForbidden:      http.StatusForbidden,
+	CodeNotFound:       http.StatusNotFound,
+	CodeConflict:       http.StatusConflict,
+	CodeTimeout:        http.StatusGatewayTimeout,
+	CodeInternalServer: http.StatusInternalServerError,
+
+	CodePreconditionFailed:   http.StatusPreconditionFailed,
+	CodePreconditionRequired: http.StatusPreconditionRequired,
+	CodeTooManyRequests:      http.StatusTooManyRequests,
+}
+
+// StatusCode returns the HTTP status for an APIError code. Unknown codes are
+// treated as server errors.
+func StatusCode(code string) int {
+	if status, ok := statusByCode[code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// TranslateError turns any error returned by the domain layer into an
+// APIError that is safe to send to clients. Driver and Elasticsearch errors
+// are mapped to a generic error of the matching kind; their text is only
+// logged. Elasticsearch errors keep the kind of their HTTP status, so a
+// request it rejected, like a bad query, stays a client error.
+func TranslateError(err error) *APIError {
+	if err == nil {
+		return nil
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	var pgErr *pgconn.PgError
+	var esErr *elastic.Error
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return errNotFound
+	case errors.As(err, &pgErr):
+		switch pgErr.Code {
+		case pgUniqueViolation:
+			return errConflict.WithDetails(map[string]interface{}{
+				"constraint": pgErr.ConstraintName,
+			})
+		case pgForeignKeyViolation, pgCheckViolation, pgInvalidTextRepr:
+			return errInvalidInput
+		}
+	case elastic.IsNotFound(err):
+		return errNotFound
+	case elastic.IsConflict(err):
+		return errConflict
+	case errors.As(err, &esErr):
+		if apiErr := errorForStatus(esErr.Status); apiErr != errInternalServer {
+			return apiErr
+		}
+	case errors.Is(err, context.DeadlineExceeded):
+		return errTimeout
+	}
+
+	logger.Debug("unhandled error", err)
+	return errInternalServer
+}
+
+func errorForStatus(statusCode int) *APIError {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return errInvalidInput
+	case http.StatusUnauthorized:
+		return errUnauthorized
+	case http.StatusForbidden:
+		return errForbidden
+	case http.StatusNotFound:
+		return errNotFound
+	case http.StatusConflict:
+		return errConflict
+	case http.StatusPreconditionFailed:
+		return errPreconditionFailed
+	case http.StatusPreconditionRequired:
+		return errPreconditionRequired
+	case http.StatusTooManyRequests:
+		return errTooManyRequests
+	default:
+		return errInternalServer
+	}
+}
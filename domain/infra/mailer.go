@@ -0,0 +1,97 @@
+package infra
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/afif-musyayyidin/hertz-boilerplate/config"
+	"github.com/google/uuid"
+)
+
+// Mail is a plain text email.
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails. NewMailer picks the implementation from MAILER.
+type Mailer interface {
+	Send(ctx context.Context, mail Mail) error
+}
+
+// NewMailer returns an SMTPMailer when MAILER is "smtp" and a FileMailer
+// otherwise.
+func NewMailer(cfg config.Config) Mailer {
+	if cfg.Mailer == "smtp" {
+		return NewSMTPMailer(cfg)
+	}
+	return NewFileMailer(cfg.MailDir, cfg.MailFrom)
+}
+
+type SMTPMailer struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+func NewSMTPMailer(cfg config.Config) *SMTPMailer {
+	m := &SMTPMailer{
+		addr: net.JoinHostPort(cfg.SMTPHost, strconv.Itoa(cfg.SMTPPort)),
+		from: cfg.MailFrom,
+	}
+	if cfg.SMTPUsername != "" {
+		m.auth = smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPHost)
+	}
+	return m
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, mail Mail) error {
+	return smtp.SendMail(m.addr, m.auth, m.from, []string{mail.To}, message(m.from, mail))
+}
+
+// FileMailer stands in for SMTP in local development: every email is written
+// to a .eml file in dir, or to the log when dir is empty.
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func NewFileMailer(dir string, from string) *FileMailer {
+	return &FileMailer{dir: dir, from: from}
+}
+
+func (m *FileMailer) Send(ctx context.Context, mail Mail) error {
+	raw := message(m.from, mail)
+	if m.dir == "" {
+		log.Printf("📧 mail to %s\n%s", mail.To, raw)
+		return nil
+	}
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405"), uuid.NewString())
+	return os.WriteFile(filepath.Join(m.dir, name), raw, 0o600)
+}
+
+func message(from string, mail Mail) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + mail.To + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", mail.Subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(mail.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
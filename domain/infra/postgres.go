@@ -2,11 +2,13 @@ package infra
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
 	"github.com/afif-musyayyidin/hertz-boilerplate/config"
+	"github.com/jackc/pgx/v5/pgconn"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
 )
@@ -68,3 +70,10 @@ func InitPostgresReplica(cfg config.Config) *sqlx.DB {
 	log.Println("✅ Connected to Postgres Replica")
 	return db
 }
+
+// IsUniqueViolation reports whether err is a violation of the unique
+// constraint or index named constraint.
+func IsUniqueViolation(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation && pgErr.ConstraintName == constraint
+}
@@ -13,9 +13,10 @@ type SuccessResponse struct {
 }
 
 type ErrorResponse struct {
-	Success bool   `json:"success"`
-	Message string `json:"message"`
-	Error   string `json:"error,omitempty"`
+	Success   bool                   `json:"success"`
+	ErrorCode string                 `json:"error_code"`
+	Message   string                 `json:"message"`
+	Details   map[string]interface{} `json:"details,omitempty"`
 }
 
 func JSONSuccess(c *app.RequestContext, data interface{}, message string) {
@@ -26,10 +27,34 @@ func JSONSuccess(c *app.RequestContext, data interface{}, message string) {
 	})
 }
 
+// JSONError writes an error detected by the handler itself, such as a body
+// that cannot be bound. err may be nil; its text is only exposed for client
+// errors.
 func JSONError(c *app.RequestContext, statusCode int, message string, err error) {
-	c.JSON(statusCode, ErrorResponse{
-		Success: false,
-		Message: message,
-		Error:   err.Error(),
+	resp := ErrorResponse{
+		Success:   false,
+		ErrorCode: errorForStatus(statusCode).ErrorCode,
+		Message:   message,
+	}
+	if err != nil && statusCode < http.StatusInternalServerError {
+		resp.Details = map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+	c.JSON(statusCode, resp)
+}
+
+// JSONErrorFrom writes an error returned by the service layer, using
+// TranslateError to pick the status code and the client-safe payload.
+func JSONErrorFrom(c *app.RequestContext, err error) {
+	apiErr := TranslateError(err)
+	if apiErr == nil {
+		apiErr = errInternalServer
+	}
+	c.JSON(StatusCode(apiErr.ErrorCode), ErrorResponse{
+		Success:   false,
+		ErrorCode: apiErr.ErrorCode,
+		Message:   apiErr.Message,
+		Details:   apiErr.Details,
 	})
 }
@@ -2,11 +2,19 @@ package main
 
 import (
 	"context"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 
 	"github.com/afif-musyayyidin/hertz-boilerplate/api/router"
 	"github.com/afif-musyayyidin/hertz-boilerplate/config"
 	_ "github.com/afif-musyayyidin/hertz-boilerplate/docs"
+	"github.com/afif-musyayyidin/hertz-boilerplate/domain/articles"
+	"github.com/afif-musyayyidin/hertz-boilerplate/domain/authors"
 	"github.com/afif-musyayyidin/hertz-boilerplate/domain/infra"
+	"github.com/afif-musyayyidin/hertz-boilerplate/middleware"
 	"github.com/cloudwego/hertz/pkg/app/server"
 	hertzSwagger "github.com/hertz-contrib/swagger"
 	swaggerFiles "github.com/swaggo/files"
@@ -24,11 +32,76 @@ func main() {
 	cfg := config.LoadConfig()
 	db := infra.InitPostgres(cfg)
 	dbReplica := infra.InitPostgresReplica(cfg)
-	ctx := context.Background()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 	es := infra.ConnectElasticsearch(cfg)
 
+	// `reindex` rebuilds the articles index from Postgres and exits.
+	if len(os.Args) > 1 && os.Args[1] == "reindex" {
+		reindexer := articles.NewReindexer(articles.NewArticleRepo(ctx, db), articles.NewArticleIndexer(es), cfg.ReindexBatchSize, os.Stdout)
+		if err := reindexer.Run(ctx); err != nil {
+			log.Fatalf("reindex failed: %v", err)
+		}
+		return
+	}
+
+	keys, err := middleware.LoadKeySet(cfg)
+	if err != nil {
+		log.Fatalf("failed to load JWT keys: %v", err)
+	}
+	verifier, err := authors.LoadEmailVerifier(cfg.EmailVerificationSecret, cfg.Development())
+	if err != nil {
+		log.Fatalf("failed to load email verification secret: %v", err)
+	}
+
+	// a legacy articles index without the current mapping fails here until
+	// `reindex` moves the articles behind the alias
+	if err := articles.NewArticleIndexer(es).EnsureIndex(ctx); err != nil {
+		log.Fatalf("failed to prepare articles index: %v", err)
+	}
+
+	clientIP, err := infra.ClientIP(cfg.TrustedProxies)
+	if err != nil {
+		log.Fatalf("invalid TRUSTED_PROXIES: %v", err)
+	}
+
+	var workers sync.WaitGroup
+	purgeJob := articles.NewPurgeJob(articles.NewArticleRepo(ctx, db), cfg.ArticlePurgeInterval, cfg.ArticlePurgeRetention)
+	publishScheduler := articles.NewPublishScheduler(articles.NewArticleRepo(ctx, db), db, cfg.PublishSchedulerInterval, cfg.PublishSchedulerBatchSize)
+	outboxRelay := articles.NewOutboxRelay(articles.NewArticleRepo(ctx, db), articles.NewArticleIndexer(es), cfg.OutboxRelayInterval, cfg.OutboxRelayBatchSize, cfg.OutboxMaxAttempts)
+	workers.Add(3)
+	go func() {
+		defer workers.Done()
+		purgeJob.Run(ctx)
+	}()
+	go func() {
+		defer workers.Done()
+		publishScheduler.Run(ctx)
+	}()
+	go func() {
+		defer workers.Done()
+		outboxRelay.Run(ctx)
+	}()
+
 	h := server.Default(server.WithHostPorts(":8080"))
-	router.SetupRouter(ctx, h, db, dbReplica, es)
+	h.SetClientIPFunc(clientIP)
+	var loginAttempts authors.LoginAttemptStore = authors.NewPostgresLoginAttemptStore(db, authors.DefaultLoginWindow)
+	if cfg.LoginAttemptStore == "memory" {
+		memoryAttempts := authors.NewMemoryLoginAttemptStore(authors.DefaultLoginWindow)
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			memoryAttempts.Run(ctx)
+		}()
+		loginAttempts = memoryAttempts
+	}
+	mail := authors.NewAccountMail(infra.NewMailer(cfg), cfg.PasswordResetURL, cfg.EmailVerificationURL)
+	router.SetupRouter(ctx, h, db, dbReplica, es, loginAttempts, mail, verifier, keys, outboxRelay)
 	h.GET("/swagger/*any", hertzSwagger.WrapHandler(swaggerFiles.Handler))
 	h.Spin()
+
+	// Spin returns once the server has shut down; stop background workers
+	// before the deferred connection cleanup runs.
+	stop()
+	workers.Wait()
 }
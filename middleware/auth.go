@@ -6,70 +6,158 @@ import (
 	"strings"
 	"time"
 
-	"github.com/afif-musyayyidin/hertz-boilerplate/config"
+	"github.com/afif-musyayyidin/hertz-boilerplate/domain/infra"
 	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func GetJwtSecret() []byte {
-	return []byte(config.LoadConfig().JWTSecret)
+type Claims struct {
+	AuthorID    string `json:"author_id"`
+	AuthorName  string `json:"author_name"`
+	AuthorEmail string `json:"author_email"`
+	Role        string `json:"role"`
+	jwt.RegisteredClaims
 }
 
-type Claims struct {
-    AuthorID string `json:"author_id"`
-    AuthorName string `json:"author_name"`
-    AuthorEmail string `json:"author_email"`
-    jwt.RegisteredClaims
+// Denylist reports whether an access token, identified by its jti, was
+// revoked before it expired.
+type Denylist interface {
+	IsRevoked(ctx context.Context, tokenID string) (bool, error)
+}
+
+// GenerateToken signs an access token. tokenID becomes the jti claim, the
+// key under which the token can be revoked.
+func (k *KeySet) GenerateToken(authorID, authorName, authorEmail, role, tokenID string, expireDuration time.Duration) (string, error) {
+	claims := Claims{
+		AuthorID:    authorID,
+		AuthorName:  authorName,
+		AuthorEmail: authorEmail,
+		Role:        role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expireDuration)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	return k.Sign(claims)
+}
+
+func AuthMiddleware(keys *KeySet, denylist Denylist) app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		authHeader := string(ctx.GetHeader("Authorization"))
+		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
+			infra.JSONError(ctx, http.StatusUnauthorized, "Missing or invalid token", nil)
+			ctx.Abort()
+			return
+		}
+
+		if !authenticate(c, ctx, strings.TrimPrefix(authHeader, "Bearer "), keys, denylist) {
+			return
+		}
+
+		ctx.Next(c)
+	}
+}
+
+// OptionalAuthMiddleware lets anonymous requests through but still rejects
+// a token that is present and invalid, so handlers can tell a logged-in
+// caller from an anonymous one by the presence of author_id.
+func OptionalAuthMiddleware(keys *KeySet, denylist Denylist) app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		authHeader := string(ctx.GetHeader("Authorization"))
+		if authHeader == "" {
+			ctx.Next(c)
+			return
+		}
+		if !strings.HasPrefix(authHeader, "Bearer ") {
+			infra.JSONError(ctx, http.StatusUnauthorized, "Missing or invalid token", nil)
+			ctx.Abort()
+			return
+		}
+
+		if !authenticate(c, ctx, strings.TrimPrefix(authHeader, "Bearer "), keys, denylist) {
+			return
+		}
+
+		ctx.Next(c)
+	}
+}
+
+// authenticate validates the token, checks it against the denylist and
+// stores its claims on the request. On failure it writes the error
+// response, aborts and returns false.
+func authenticate(c context.Context, ctx *app.RequestContext, tokenString string, keys *KeySet, denylist Denylist) bool {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, keys.Keyfunc, jwt.WithValidMethods(validMethods))
+	if err != nil || !token.Valid {
+		infra.JSONError(ctx, http.StatusUnauthorized, "Invalid token", nil)
+		ctx.Abort()
+		return false
+	}
+
+	claims, ok := token.Claims.(*Claims)
+	if !ok || claims.ID == "" || claims.ExpiresAt == nil {
+		infra.JSONError(ctx, http.StatusUnauthorized, "Invalid token claims", nil)
+		ctx.Abort()
+		return false
+	}
+
+	revoked, err := denylist.IsRevoked(c, claims.ID)
+	if err != nil {
+		infra.JSONError(ctx, http.StatusInternalServerError, "Internal Server Error", err)
+		ctx.Abort()
+		return false
+	}
+	if revoked {
+		infra.JSONError(ctx, http.StatusUnauthorized, "Token revoked", nil)
+		ctx.Abort()
+		return false
+	}
+
+	// Save claims to context for next handlers
+	ctx.Set("author_id", claims.AuthorID)
+	ctx.Set("author_name", claims.AuthorName)
+	ctx.Set("author_email", claims.AuthorEmail)
+	ctx.Set("author_role", claims.Role)
+	ctx.Set("token_id", claims.ID)
+	ctx.Set("token_expires_at", claims.ExpiresAt.Time)
+	return true
+}
+
+// RequireRole only lets through callers whose token carries one of roles.
+// It must run after AuthMiddleware.
+func RequireRole(roles ...string) app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		if !hasRole(ctx, roles) {
+			infra.JSONError(ctx, http.StatusForbidden, "Forbidden", nil)
+			ctx.Abort()
+			return
+		}
+		ctx.Next(c)
+	}
 }
 
-func GenerateToken(authorID, authorName, authorEmail string, expireDuration time.Duration) (string, error) {
-    claims := Claims{
-        AuthorID: authorID,
-        AuthorName: authorName,
-        AuthorEmail: authorEmail,
-        RegisteredClaims: jwt.RegisteredClaims{
-            ExpiresAt: jwt.NewNumericDate(time.Now().Add(expireDuration)),
-            IssuedAt:  jwt.NewNumericDate(time.Now()),
-        },
-    }
-
-    token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-    return token.SignedString(GetJwtSecret())
+// RequireSelfOrRole is RequireRole that also lets through the author whose
+// id is the path parameter param, e.g. for an author updating their own
+// account. It must run after AuthMiddleware.
+func RequireSelfOrRole(param string, roles ...string) app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		self := ctx.GetString("author_id")
+		if (self == "" || !strings.EqualFold(self, ctx.Param(param))) && !hasRole(ctx, roles) {
+			infra.JSONError(ctx, http.StatusForbidden, "Forbidden", nil)
+			ctx.Abort()
+			return
+		}
+		ctx.Next(c)
+	}
 }
 
-func AuthMiddleware() app.HandlerFunc {
-    return func(c context.Context, ctx *app.RequestContext) {
-        authHeader := string(ctx.GetHeader("Authorization"))
-        if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-            ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "Missing or invalid token"})
-            ctx.Abort()
-            return
-        }
-
-        tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-
-        token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-            return GetJwtSecret(), nil
-        })
-
-        if err != nil || !token.Valid {
-            ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid token"})
-            ctx.Abort()
-            return
-        }
-
-        claims, ok := token.Claims.(*Claims)
-        if !ok {
-            ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid token claims"})
-            ctx.Abort()
-            return
-        }
-
-        // Save claims to context for next handlers
-        ctx.Set("author_id", claims.AuthorID)
-        ctx.Set("author_name", claims.AuthorName)
-        ctx.Set("author_email", claims.AuthorEmail)
-
-        ctx.Next(c)
-    }
+func hasRole(ctx *app.RequestContext, roles []string) bool {
+	role := ctx.GetString("author_role")
+	for _, allowed := range roles {
+		if role != "" && role == allowed {
+			return true
+		}
+	}
+	return false
 }
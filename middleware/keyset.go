@@ -0,0 +1,222 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"sort"
+
+	"github.com/afif-musyayyidin/hertz-boilerplate/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const minRSAKeyBits = 2048
+
+// KeySet signs access tokens with one private key and verifies them with any
+// of its public keys, picked by the kid header. Keeping the previous public
+// key in the set while a new signing key is rolled out lets tokens signed
+// before the switch stay valid until they expire.
+type KeySet struct {
+	signingKeyID string
+	signingKey   crypto.Signer
+	method       jwt.SigningMethod
+	verification map[string]crypto.PublicKey
+}
+
+// JWK is one public key in JSON Web Key format (RFC 7517).
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// LoadKeySet reads the PEM signing key and the extra PEM public keys named in
+// cfg. main loads it once at startup, so a missing or bad key stops the
+// server. Only in development may the signing key file be left out; a
+// throwaway Ed25519 key is generated then, whose tokens die with the process.
+func LoadKeySet(cfg config.Config) (*KeySet, error) {
+	verification := make(map[string]crypto.PublicKey, len(cfg.JWTVerificationKeys))
+	for kid, path := range cfg.JWTVerificationKeys {
+		key, err := readPublicKey(path)
+		if err != nil {
+			return nil, fmt.Errorf("verification key %q: %w", kid, err)
+		}
+		verification[kid] = key
+	}
+
+	if cfg.JWTSigningKeyFile == "" {
+		if !cfg.Development() {
+			return nil, errors.New("JWT_SIGNING_KEY_FILE is not set; only APP_ENV=development may run with a temporary key")
+		}
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		suffix := make([]byte, 4)
+		if _, err := rand.Read(suffix); err != nil {
+			return nil, err
+		}
+		log.Printf("⚠️ JWT_SIGNING_KEY_FILE is not set, signing tokens with a temporary key")
+		return NewKeySet("dev-"+hex.EncodeToString(suffix), private, verification)
+	}
+
+	signingKey, err := readPrivateKey(cfg.JWTSigningKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("signing key: %w", err)
+	}
+	return NewKeySet(cfg.JWTSigningKeyID, signingKey, verification)
+}
+
+// NewKeySet signs with signingKey, an RSA or Ed25519 private key, under
+// signingKeyID. Its public key is added to verification.
+func NewKeySet(signingKeyID string, signingKey crypto.Signer, verification map[string]crypto.PublicKey) (*KeySet, error) {
+	if signingKeyID == "" {
+		return nil, errors.New("signing key id is empty")
+	}
+	method, err := signingMethod(signingKey.Public())
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]crypto.PublicKey, len(verification)+1)
+	for kid, key := range verification {
+		if _, err := signingMethod(key); err != nil {
+			return nil, fmt.Errorf("verification key %q: %w", kid, err)
+		}
+		keys[kid] = key
+	}
+	keys[signingKeyID] = signingKey.Public()
+	return &KeySet{
+		signingKeyID: signingKeyID,
+		signingKey:   signingKey,
+		method:       method,
+		verification: keys,
+	}, nil
+}
+
+// Sign signs claims with the signing key and names it in the kid header.
+func (k *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.method, claims)
+	token.Header["kid"] = k.signingKeyID
+	return token.SignedString(k.signingKey)
+}
+
+// Keyfunc picks the verification key named by the kid header of token. The
+// algorithm has to match the key, so an RSA public key is never used as an
+// HMAC secret.
+func (k *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := k.verification[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	method, err := signingMethod(key)
+	if err != nil {
+		return nil, err
+	}
+	if token.Method.Alg() != method.Alg() {
+		return nil, fmt.Errorf("key %q does not sign with %s", kid, token.Method.Alg())
+	}
+	return key, nil
+}
+
+// JWKS returns the public verification keys, sorted by kid.
+func (k *KeySet) JWKS() JWKS {
+	kids := make([]string, 0, len(k.verification))
+	for kid := range k.verification {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	jwks := JWKS{Keys: make([]JWK, 0, len(kids))}
+	for _, kid := range kids {
+		jwk := JWK{KeyID: kid, Use: "sig"}
+		switch key := k.verification[kid].(type) {
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Algorithm = jwt.SigningMethodEdDSA.Alg()
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(key)
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.Algorithm = jwt.SigningMethodRS256.Alg()
+			jwk.N = base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return jwks
+}
+
+// validMethods are the algorithms a token may be signed with.
+var validMethods = []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}
+
+func signingMethod(key crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch key := key.(type) {
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	case *rsa.PublicKey:
+		if key.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("RSA key shorter than %d bits", minRSAKeyBits)
+		}
+		return jwt.SigningMethodRS256, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %T, want RSA or Ed25519", key)
+}
+
+func readPrivateKey(path string) (crypto.Signer, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+	return signer, nil
+}
+
+func readPublicKey(path string) (crypto.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data", path)
+	}
+	return block, nil
+}
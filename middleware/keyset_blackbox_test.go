@@ -0,0 +1,214 @@
+package middleware_test
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/afif-musyayyidin/hertz-boilerplate/config"
+	"github.com/afif-musyayyidin/hertz-boilerplate/middleware"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRSAKey(t *testing.T, bits int) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	require.NoError(t, err)
+	return key
+}
+
+func newEd25519Key(t *testing.T) ed25519.PrivateKey {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+// writePEM writes key as a PKCS#8 private key or a PKIX public key and
+// returns the path.
+func writePEM(t *testing.T, name string, key interface{}) string {
+	var block *pem.Block
+	switch key := key.(type) {
+	case crypto.Signer:
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		require.NoError(t, err)
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	default:
+		der, err := x509.MarshalPKIXPublicKey(key)
+		require.NoError(t, err)
+		block = &pem.Block{Type: "PUBLIC KEY", Bytes: der}
+	}
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(block), 0o600))
+	return path
+}
+
+func sign(t *testing.T, keys *middleware.KeySet) string {
+	token, err := keys.Sign(jwt.MapClaims{"sub": "budi"})
+	require.NoError(t, err)
+	return token
+}
+
+func parse(keys *middleware.KeySet, token string) (*jwt.Token, error) {
+	return jwt.Parse(token, keys.Keyfunc)
+}
+
+func TestKeySetRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		alg string
+		key crypto.Signer
+	}{
+		{alg: "RS256", key: newRSAKey(t, 2048)},
+		{alg: "EdDSA", key: newEd25519Key(t)},
+	} {
+		t.Run(tc.alg, func(t *testing.T) {
+			keys, err := middleware.NewKeySet("k1", tc.key, nil)
+			require.NoError(t, err)
+
+			token, err := parse(keys, sign(t, keys))
+			require.NoError(t, err)
+			assert.True(t, token.Valid)
+			assert.Equal(t, tc.alg, token.Header["alg"])
+			assert.Equal(t, "k1", token.Header["kid"])
+			sub, err := token.Claims.GetSubject()
+			require.NoError(t, err)
+			assert.Equal(t, "budi", sub)
+		})
+	}
+}
+
+func TestKeySetRotation(t *testing.T) {
+	oldKey := newEd25519Key(t)
+	oldKeys, err := middleware.NewKeySet("2025-09", oldKey, nil)
+	require.NoError(t, err)
+	oldToken := sign(t, oldKeys)
+
+	newKeys, err := middleware.LoadKeySet(config.Config{
+		JWTSigningKeyFile: writePEM(t, "signing.pem", newRSAKey(t, 2048)),
+		JWTSigningKeyID:   "2025-10",
+		JWTVerificationKeys: map[string]string{
+			"2025-09": writePEM(t, "2025-09.pub", oldKey.Public()),
+		},
+	})
+	require.NoError(t, err)
+
+	// tokens signed before the switch stay valid
+	token, err := parse(newKeys, oldToken)
+	require.NoError(t, err)
+	assert.Equal(t, "2025-09", token.Header["kid"])
+
+	token, err = parse(newKeys, sign(t, newKeys))
+	require.NoError(t, err)
+	assert.Equal(t, "2025-10", token.Header["kid"])
+
+	// the old key set does not know the new key
+	_, err = parse(oldKeys, sign(t, newKeys))
+	assert.Error(t, err)
+}
+
+func TestKeySetRejectsUnknownKeyID(t *testing.T) {
+	keys, err := middleware.NewKeySet("k1", newEd25519Key(t), nil)
+	require.NoError(t, err)
+	other, err := middleware.NewKeySet("k2", newEd25519Key(t), nil)
+	require.NoError(t, err)
+
+	_, err = parse(keys, sign(t, other))
+	assert.ErrorContains(t, err, `unknown key id "k2"`)
+
+	// same kid, different key
+	impostor, err := middleware.NewKeySet("k1", newEd25519Key(t), nil)
+	require.NoError(t, err)
+	_, err = parse(keys, sign(t, impostor))
+	assert.ErrorIs(t, err, jwt.ErrTokenSignatureInvalid)
+}
+
+func TestKeySetRejectsAlgorithmMismatch(t *testing.T) {
+	rsaKey := newRSAKey(t, 2048)
+	keys, err := middleware.NewKeySet("rsa", rsaKey, nil)
+	require.NoError(t, err)
+
+	// the RSA public key must never be used as an HMAC secret
+	der, err := x509.MarshalPKIXPublicKey(rsaKey.Public())
+	require.NoError(t, err)
+	hmac := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "budi"})
+	hmac.Header["kid"] = "rsa"
+	forged, err := hmac.SignedString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	require.NoError(t, err)
+	_, err = parse(keys, forged)
+	assert.ErrorContains(t, err, `key "rsa" does not sign with HS256`)
+
+	eddsa := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.MapClaims{"sub": "budi"})
+	eddsa.Header["kid"] = "rsa"
+	forged, err = eddsa.SignedString(newEd25519Key(t))
+	require.NoError(t, err)
+	_, err = parse(keys, forged)
+	assert.ErrorContains(t, err, `key "rsa" does not sign with EdDSA`)
+}
+
+func TestKeySetRejectsShortRSAKey(t *testing.T) {
+	short := newRSAKey(t, 1024)
+
+	_, err := middleware.NewKeySet("short", short, nil)
+	assert.ErrorContains(t, err, "RSA key shorter than 2048 bits")
+
+	_, err = middleware.NewKeySet("k1", newEd25519Key(t), map[string]crypto.PublicKey{
+		"short": short.Public(),
+	})
+	assert.ErrorContains(t, err, "RSA key shorter than 2048 bits")
+
+	_, err = middleware.LoadKeySet(config.Config{
+		JWTSigningKeyFile: writePEM(t, "short.pem", short),
+		JWTSigningKeyID:   "short",
+	})
+	assert.ErrorContains(t, err, "RSA key shorter than 2048 bits")
+}
+
+func TestLoadKeySetNeedsSigningKeyOutsideDevelopment(t *testing.T) {
+	_, err := middleware.LoadKeySet(config.Config{AppEnv: "production"})
+	assert.ErrorContains(t, err, "JWT_SIGNING_KEY_FILE is not set")
+
+	keys, err := middleware.LoadKeySet(config.Config{AppEnv: "development"})
+	require.NoError(t, err)
+	_, err = parse(keys, sign(t, keys))
+	assert.NoError(t, err)
+}
+
+func TestKeySetJWKS(t *testing.T) {
+	rsaKey := newRSAKey(t, 2048)
+	edKey := newEd25519Key(t)
+	keys, err := middleware.NewKeySet("b-rsa", rsaKey, map[string]crypto.PublicKey{
+		"a-ed25519": edKey.Public(),
+	})
+	require.NoError(t, err)
+
+	jwks := keys.JWKS()
+	require.Len(t, jwks.Keys, 2)
+
+	ed := jwks.Keys[0]
+	assert.Equal(t, "a-ed25519", ed.KeyID)
+	assert.Equal(t, "OKP", ed.KeyType)
+	assert.Equal(t, "EdDSA", ed.Algorithm)
+	assert.Equal(t, "Ed25519", ed.Curve)
+	assert.Equal(t, "sig", ed.Use)
+	assert.Equal(t, base64.RawURLEncoding.EncodeToString(edKey.Public().(ed25519.PublicKey)), ed.X)
+	assert.Empty(t, ed.N)
+
+	rs := jwks.Keys[1]
+	assert.Equal(t, "b-rsa", rs.KeyID)
+	assert.Equal(t, "RSA", rs.KeyType)
+	assert.Equal(t, "RS256", rs.Algorithm)
+	assert.Equal(t, "sig", rs.Use)
+	assert.Equal(t, "AQAB", rs.E)
+	n, err := base64.RawURLEncoding.DecodeString(rs.N)
+	require.NoError(t, err)
+	assert.Equal(t, 0, new(big.Int).SetBytes(n).Cmp(rsaKey.N))
+	assert.Empty(t, rs.X)
+}